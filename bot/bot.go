@@ -0,0 +1,114 @@
+package bot
+
+import (
+	"tetris/engine"
+)
+
+// Bot picks where to put each piece by scoring every place it can reach
+type Bot struct {
+	Weights Weights
+
+	// also search every place the next piece can go after each placement and score them together,
+	// this plays a lot better but is a lot slower
+	Lookahead bool
+}
+
+// returns a bot that plays with the weights and no lookahead
+func New(w Weights) *Bot {
+	return &Bot{Weights: w}
+}
+
+// finds the best placement for the falling piece, or for the held one if holding is allowed,
+// returns false if there is no falling piece, nowhere it can go or the game isn't one CheckGame allows
+func (b *Bot) Best(g *engine.Game) (Placement, bool) {
+	if g.CurrentPiece == nil || CheckGame(g) != nil {
+		return Placement{}, false
+	}
+	grid := GridFromGame(g)
+	queue := g.PeekNext(2)
+
+	// the piece that comes out of hold, and the piece after whichever one is placed
+	next := queue[0]
+	var hold engine.Tetro
+	holdNext := queue[0]
+	if g.CanHold {
+		hold = engine.Tetro(g.HeldPiece)
+		if hold == 0 && len(queue) > 1 {
+			hold, holdNext = queue[0], queue[1]
+		}
+	}
+
+	best, found := b.best(&grid, *g.CurrentPiece, next)
+	// holding a piece of the same kind can't go anywhere new
+	if hold != 0 && hold != g.CurrentPiece.Tetro {
+		if p, ok := b.best(&grid, *hold.NewTetromino(), holdNext); ok && (!found || p.Score > best.Score) {
+			p.Hold = true
+			best, found = p, true
+		}
+	}
+	return best, found
+}
+
+// the best place for the piece, thinking about the next piece too with lookahead
+func (b *Bot) best(grid *Grid, piece engine.Tetromino, next engine.Tetro) (Placement, bool) {
+	var best Placement
+	found := false
+	for _, p := range grid.Placements(piece) {
+		after, lines, eroded := grid.place(p.Piece.Shape)
+		p.Score = b.Weights.score(&after, p.Piece.Shape, lines, eroded)
+		if b.Lookahead && p.Score > topOutScore {
+			p.Score += b.bestScore(&after, next)
+		}
+		if !found || p.Score > best.Score {
+			best, found = p, true
+		}
+	}
+	return best, found
+}
+
+// the score of the best place for a new piece of the tetro on the grid
+func (b *Bot) bestScore(grid *Grid, t engine.Tetro) float64 {
+	best := topOutScore
+	for _, p := range grid.Placements(*t.NewTetromino()) {
+		after, lines, eroded := grid.place(p.Piece.Shape)
+		if score := b.Weights.score(&after, p.Piece.Shape, lines, eroded); score > best {
+			best = score
+		}
+	}
+	return best
+}
+
+// makes the moves of the placement on the game and hard drops the piece
+func Apply(g *engine.Game, p Placement) {
+	if p.Hold {
+		g.HoldTetro()
+	}
+	for _, m := range p.Moves {
+		switch m {
+		case MoveLeft:
+			g.MoveLeft()
+		case MoveRight:
+			g.MoveRight()
+		case MoveClockWise:
+			g.RotateClockWise()
+		case MoveCounterClockWise:
+			g.RotateCounterClockWise()
+		case Move180:
+			g.Rotate180()
+		case MoveDrop:
+			for g.GravityDrop() {
+			}
+		}
+	}
+	g.HardDrop()
+}
+
+// places the falling piece where the bot thinks is best, returns false if there was nothing to place
+func (b *Bot) Play(g *engine.Game) bool {
+	p, ok := b.Best(g)
+	if !ok {
+		return false
+	}
+	Apply(g, p)
+	return true
+}
@@ -0,0 +1,77 @@
+package bot
+
+import (
+	"testing"
+
+	"tetris/engine"
+)
+
+// makes a game with rows of the board filled in from the bottom up and an I falling
+func newTuckGame(rows []string) engine.Game {
+	g := engine.NewGame(1, engine.NewBagRandomizer(1, 1))
+	g.SetNextTetroFromBag()
+	for i, row := range rows {
+		for j, c := range row {
+			if c == 'X' {
+				g.PlayingBoard.Set(engine.Point{Row: i, Col: j}, engine.GarbagePixel)
+			}
+		}
+	}
+	g.CurrentPiece = engine.Tetro(4).NewTetromino()
+	return g
+}
+
+func TestApply(t *testing.T) {
+	g := newTuckGame(tuckRows)
+	grid := GridFromGame(&g)
+	var tuck Placement
+	for _, p := range grid.Placements(*g.CurrentPiece) {
+		if sameCells(p.Piece.Shape, tucked) {
+			tuck = p
+		}
+	}
+	if tuck.Moves == nil {
+		t.Fatal("no placement tucks the I under the overhang")
+	}
+
+	Apply(&g, tuck)
+	for _, p := range tucked {
+		if !g.PlayingBoard.Taken(p) {
+			t.Fatalf("the I wasn't locked at %v after %v", p, tuck.Moves)
+		}
+	}
+	if g.PiecesPlaced != 1 {
+		t.Fatalf("%d pieces placed, want 1", g.PiecesPlaced)
+	}
+}
+
+func TestApplyHold(t *testing.T) {
+	g := newTuckGame(nil)
+	next := g.PeekNext(1)[0]
+	Apply(&g, Placement{Hold: true})
+	if engine.Tetro(g.HeldPiece) != 4 {
+		t.Fatalf("held %d, want the I", g.HeldPiece)
+	}
+
+	// the piece that came out of the queue is the one that was hard dropped
+	dropped := false
+	for _, p := range next.NewTetromino().Shape {
+		if g.PlayingBoard.Taken(engine.Point{Row: 0, Col: p.Col}) {
+			dropped = true
+		}
+	}
+	if !dropped || g.PiecesPlaced != 1 {
+		t.Fatalf("the piece out of the queue wasn't dropped, %d pieces placed", g.PiecesPlaced)
+	}
+}
+
+func TestPlay(t *testing.T) {
+	// a well in the right hand column with three rows either side of it, only an I standing in it clears them
+	g := newTuckGame([]string{"XXXXXXXXX.", "XXXXXXXXX.", "XXXXXXXXX.", "XXXXXXXXX."})
+	if !New(DefaultWeights()).Play(&g) {
+		t.Fatal("the bot didn't play")
+	}
+	if g.LinesCleared != 4 || !g.PlayingBoard.Empty() {
+		t.Fatalf("the bot cleared %d lines, want a tetris from the well", g.LinesCleared)
+	}
+}
@@ -0,0 +1,143 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/bits"
+	"os"
+
+	"tetris/engine"
+)
+
+// Weights is how much each feature of the stack counts towards the score of a placement,
+// the defaults are the ones El-Tetris found for Pierre Dellacherie's features
+type Weights struct {
+	// how high the piece was placed, the middle of its lowest and highest rows
+	LandingHeight float64 `json:"landing_height"`
+
+	// lines cleared times how many pixels of the piece went with them
+	ErodedCells float64 `json:"eroded_cells"`
+
+	// how many lines were cleared
+	LinesCleared float64 `json:"lines_cleared"`
+
+	// how many times a row goes from empty to taken or back, the walls count as taken
+	RowTransitions float64 `json:"row_transitions"`
+
+	// how many times a column goes from empty to taken or back, the floor counts as taken
+	ColumnTransitions float64 `json:"column_transitions"`
+
+	// empty pixels with something above them
+	Holes float64 `json:"holes"`
+
+	// empty pixels with both sides taken, a well 3 deep counts 1+2+3
+	Wells float64 `json:"wells"`
+
+	// the heights of every column added up
+	AggregateHeight float64 `json:"aggregate_height"`
+
+	// the difference in height between each column and the next, added up
+	Bumpiness float64 `json:"bumpiness"`
+}
+
+// the weights from El-Tetris, which clear millions of lines on average
+func DefaultWeights() Weights {
+	return Weights{
+		LandingHeight:     -4.500158825082766,
+		ErodedCells:       3.4181268101392694,
+		RowTransitions:    -3.2178882868487753,
+		ColumnTransitions: -9.348695305445199,
+		Holes:             -7.899265427351652,
+		Wells:             -3.3855972247263626,
+	}
+}
+
+// reads weights from a JSON file, any weight the file leaves out is 0
+func LoadWeights(path string) (Weights, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Weights{}, err
+	}
+	var w Weights
+	if err := json.Unmarshal(data, &w); err != nil {
+		return Weights{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return w, nil
+}
+
+// the score of a placement that tops out, lower than any real placement can score
+const topOutScore = -1e9
+
+// scores the grid left after placing a piece, higher is better
+func (w Weights) score(grid *Grid, piece engine.Shape, lines, eroded int) float64 {
+	if grid.toppedOut() {
+		return topOutScore
+	}
+
+	low, high := piece[0].Row, piece[0].Row
+	for _, p := range piece {
+		if p.Row < low {
+			low = p.Row
+		}
+		if p.Row > high {
+			high = p.Row
+		}
+	}
+	// the piece was placed before the lines under it were cleared, so it landed that much higher
+	landing := float64(low+high)/2 + 1
+
+	// walls are taken pixels either side of each row, and the floor is a taken row under the board
+	const walls = 1 | 1<<(engine.WidthOfBoardInPixels+1)
+	var rowTransitions, columnTransitions, holes, wells, aggregate, bumpiness int
+	var covered uint16
+	var depth [engine.WidthOfBoardInPixels]int
+	for i := engine.HeightOfBoardInPixels - 1; i >= 0; i-- {
+		row := grid[i]
+		below := uint16(fullRow)
+		if i > 0 {
+			below = grid[i-1]
+		}
+
+		walled := row<<1 | walls
+		rowTransitions += bits.OnesCount16((walled ^ walled>>1) & (fullRow<<1 | 1))
+		columnTransitions += bits.OnesCount16(row ^ below)
+		holes += bits.OnesCount16(covered &^ row)
+		covered |= row
+
+		// counting wells from the top down so a deeper pixel of the same well counts for more
+		well := ^row & (walled >> 2) & (walled & fullRow)
+		for j := range depth {
+			if well&(1<<j) != 0 {
+				depth[j]++
+				wells += depth[j]
+			} else {
+				depth[j] = 0
+			}
+		}
+	}
+
+	heights := grid.heights()
+	for j := range heights {
+		aggregate += heights[j]
+		if j > 0 {
+			bumpiness += abs(heights[j] - heights[j-1])
+		}
+	}
+
+	return w.LandingHeight*landing +
+		w.ErodedCells*float64(lines*eroded) +
+		w.LinesCleared*float64(lines) +
+		w.RowTransitions*float64(rowTransitions) +
+		w.ColumnTransitions*float64(columnTransitions) +
+		w.Holes*float64(holes) +
+		w.Wells*float64(wells) +
+		w.AggregateHeight*float64(aggregate) +
+		w.Bumpiness*float64(bumpiness)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,47 @@
+package bot
+
+import (
+	"testing"
+
+	"tetris/engine"
+)
+
+func TestFeatures(t *testing.T) {
+	// heights 2 2 2 1 2 1 1 2 3 0, a hole under column 1, a well 3 deep in column 9 and two 1 deep
+	grid := gridFromRows([]string{
+		"X.XXXXXXX.",
+		"XXX.X..XX.",
+		"........X.",
+	})
+	piece := engine.Shape{{Row: 2, Col: 8}}
+
+	tests := []struct {
+		name    string
+		weights Weights
+		want    float64
+	}{
+		{"holes", Weights{Holes: 1}, 1},
+		{"wells", Weights{Wells: 1}, 1 + 1 + (1 + 2 + 3)},
+		{"aggregate height", Weights{AggregateHeight: 1}, 16},
+		{"bumpiness", Weights{Bumpiness: 1}, 0 + 0 + 1 + 1 + 1 + 0 + 1 + 1 + 3},
+		{"landing height", Weights{LandingHeight: 1}, 3},
+		{"lines and eroded cells", Weights{LinesCleared: 1, ErodedCells: 10}, 2 + 10*2*3},
+	}
+	for _, test := range tests {
+		lines, eroded := 0, 0
+		if test.weights.LinesCleared != 0 {
+			lines, eroded = 2, 3
+		}
+		if got := test.weights.score(&grid, piece, lines, eroded); got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestToppedOutScore(t *testing.T) {
+	var grid Grid
+	grid[engine.NonHiddenPixelHeight] = 1
+	if got := DefaultWeights().score(&grid, engine.Shape{{Row: engine.NonHiddenPixelHeight}}, 0, 0); got != topOutScore {
+		t.Fatalf("a topped out grid scored %v, want %v", got, topOutScore)
+	}
+}
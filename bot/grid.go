@@ -0,0 +1,103 @@
+// Package bot plays the game by itself, it finds every place the falling piece (or the held one) can reach
+// and picks the one that leaves the best looking stack, scored by a weighted sum of features of the stack,
+// it can also hand the choice to a bot in another program that speaks the Tetris Bot Protocol
+package bot
+
+import (
+	"fmt"
+
+	"tetris/engine"
+)
+
+// Grid is the locked stack without the falling piece, one bitmask per row from the bottom up,
+// bit j of a row is set when column j is taken, this is much quicker to copy and search than the board
+type Grid [engine.HeightOfBoardInPixels]uint16
+
+// a row with every column taken
+const fullRow = 1<<engine.WidthOfBoardInPixels - 1
+
+// checks the bots can play the game, a grid only holds a standard board and the bots only know the tetrominoes
+func CheckGame(g *engine.Game) error {
+	if g.PlayingBoard.Width != engine.WidthOfBoardInPixels || g.PlayingBoard.Height != engine.HeightOfBoardInPixels {
+		return fmt.Errorf("bots can only play on a %dx%d board, not %dx%d",
+			engine.WidthOfBoardInPixels, engine.NonHiddenPixelHeight, g.PlayingBoard.Width, g.PlayingBoard.Visible)
+	}
+	if g.Pieces.Name != engine.Tetrominoes.Name {
+		return fmt.Errorf("bots can only play with tetrominoes, not %s", g.Pieces.Name)
+	}
+	return nil
+}
+
+// copies the stack of the game into a grid, the game has to be one CheckGame allows
+func GridFromGame(g *engine.Game) Grid {
+	var grid Grid
+	copy(grid[:], g.PlayingBoard.Rows)
+	return grid
+}
+
+// is the pixel taken, everything outside the board counts as taken
+func (grid *Grid) taken(row, col int) bool {
+	if row < 0 || row >= engine.HeightOfBoardInPixels || col < 0 || col >= engine.WidthOfBoardInPixels {
+		return true
+	}
+	return grid[row]&(1<<col) != 0
+}
+
+// checks if a shape is inside the board and only covers empty pixels
+func (grid *Grid) fits(s engine.Shape) bool {
+	for _, p := range s {
+		if grid.taken(p.Row, p.Col) {
+			return false
+		}
+	}
+	return true
+}
+
+// returns the grid with the shape locked into it and full rows cleared, along with how many rows were cleared
+// and how many pixels of the shape went with them
+func (grid Grid) place(s engine.Shape) (Grid, int, int) {
+	for _, p := range s {
+		grid[p.Row] |= 1 << p.Col
+	}
+
+	var after Grid
+	lines, eroded, row := 0, 0, 0
+	for i := 0; i < engine.HeightOfBoardInPixels; i++ {
+		if grid[i] == fullRow {
+			lines++
+			for _, p := range s {
+				if p.Row == i {
+					eroded++
+				}
+			}
+			continue
+		}
+		after[row] = grid[i]
+		row++
+	}
+	return after, lines, eroded
+}
+
+// does the grid have anything above the visible part of the board, which ends the game
+func (grid *Grid) toppedOut() bool {
+	for i := engine.NonHiddenPixelHeight; i < engine.HeightOfBoardInPixels; i++ {
+		if grid[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// how tall each column is, the row above its highest taken pixel
+func (grid *Grid) heights() [engine.WidthOfBoardInPixels]int {
+	var heights [engine.WidthOfBoardInPixels]int
+	for j := range heights {
+		for i := engine.HeightOfBoardInPixels - 1; i >= 0; i-- {
+			if grid[i]&(1<<j) != 0 {
+				heights[j] = i + 1
+				break
+			}
+		}
+	}
+	return heights
+}
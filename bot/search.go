@@ -0,0 +1,169 @@
+package bot
+
+import (
+	"sort"
+
+	"tetris/engine"
+)
+
+// Move is one thing the bot does to the falling piece on its way to where it's going
+type Move int
+
+const (
+	MoveLeft Move = iota
+	MoveRight
+	MoveClockWise
+	MoveCounterClockWise
+	Move180
+
+	// drops the piece as far as it goes without locking it, so it can still be tucked or spun after
+	MoveDrop
+)
+
+// every move, in the order they are tried
+var moves = []Move{MoveLeft, MoveRight, MoveClockWise, MoveCounterClockWise, Move180, MoveDrop}
+
+// Placement is somewhere the piece can be locked and how to get it there
+type Placement struct {
+	// hold before moving, the moves are then for the piece that comes out of hold
+	Hold bool
+
+	// the moves to make before hard dropping the piece
+	Moves []Move
+
+	// the piece where it locks
+	Piece engine.Tetromino
+
+	// what the bot thinks of the placement, higher is better
+	Score float64
+}
+
+// a piece position the search has reached, the same shape in the same place can be reached facing different ways
+type position struct {
+	rotation engine.Rotation
+	pivot    engine.Point
+}
+
+// a step of the search, the move that reached a piece position from the one at parent
+type node struct {
+	piece  engine.Tetromino
+	move   Move
+	parent int
+}
+
+// returns the piece moved by rows and cols
+func shifted(t engine.Tetromino, rows, cols int) engine.Tetromino {
+	shape := make(engine.Shape, len(t.Shape))
+	for i, p := range t.Shape {
+		shape[i] = engine.Point{Row: p.Row + rows, Col: p.Col + cols}
+	}
+	t.Shape = shape
+	t.Pivot.Row += 2 * rows
+	t.Pivot.Col += 2 * cols
+	return t
+}
+
+// returns the piece after the move, or false if it can't be made
+func (grid *Grid) apply(t engine.Tetromino, m Move) (engine.Tetromino, bool) {
+	switch m {
+	case MoveLeft, MoveRight:
+		cols := -1
+		if m == MoveRight {
+			cols = 1
+		}
+		for _, p := range t.Shape {
+			if grid.taken(p.Row, p.Col+cols) {
+				return t, false
+			}
+		}
+		return shifted(t, 0, cols), true
+	case MoveClockWise:
+		rotated, _, ok := t.Rotated(1, grid.fits)
+		return rotated, ok
+	case MoveCounterClockWise:
+		rotated, _, ok := t.Rotated(-1, grid.fits)
+		return rotated, ok
+	case Move180:
+		rotated, _, ok := t.Rotated(2, grid.fits)
+		return rotated, ok
+	}
+	rows := grid.dropDistance(t.Shape)
+	return shifted(t, -rows, 0), rows > 0
+}
+
+// how many rows the shape can fall before it lands
+func (grid *Grid) dropDistance(s engine.Shape) int {
+	for rows := 1; ; rows++ {
+		for _, p := range s {
+			if grid.taken(p.Row-rows, p.Col) {
+				return rows - 1
+			}
+		}
+	}
+}
+
+// a key for the pixels a shape covers, so the same landing reached facing different ways is only kept once
+func shapeKey(s engine.Shape) uint64 {
+	cells := make([]int, len(s))
+	for i, p := range s {
+		cells[i] = p.Row*engine.WidthOfBoardInPixels + p.Col
+	}
+	sort.Ints(cells)
+	var key uint64
+	for _, c := range cells {
+		key = key<<8 | uint64(c)
+	}
+	return key
+}
+
+// finds every place the piece can lock from where it is now, searching breadth first over every move
+// so the moves to each place are as few as they can be, this finds tucks and spins under overhangs too
+func (grid *Grid) Placements(start engine.Tetromino) []Placement {
+	if !grid.fits(start.Shape) {
+		return nil
+	}
+
+	nodes := []node{{piece: start, parent: -1}}
+	seen := map[position]bool{{start.Rotation, start.Pivot}: true}
+	landed := map[uint64]bool{}
+	var placements []Placement
+
+	for i := 0; i < len(nodes); i++ {
+		piece := nodes[i].piece
+		if grid.dropDistance(piece.Shape) == 0 {
+			if key := shapeKey(piece.Shape); !landed[key] {
+				landed[key] = true
+				placements = append(placements, Placement{Moves: path(nodes, i), Piece: piece})
+			}
+		}
+
+		for _, m := range moves {
+			next, ok := grid.apply(piece, m)
+			if !ok {
+				continue
+			}
+			pos := position{next.Rotation, next.Pivot}
+			if seen[pos] {
+				continue
+			}
+			seen[pos] = true
+			nodes = append(nodes, node{piece: next, move: m, parent: i})
+		}
+	}
+	return placements
+}
+
+// the moves that lead to nodes[i] from the start of the search, leaving off a drop at the end since hard drop does that
+func path(nodes []node, i int) []Move {
+	var moves []Move
+	for ; nodes[i].parent != -1; i = nodes[i].parent {
+		moves = append(moves, nodes[i].move)
+	}
+	for l, r := 0, len(moves)-1; l < r; l, r = l+1, r-1 {
+		moves[l], moves[r] = moves[r], moves[l]
+	}
+	if len(moves) > 0 && moves[len(moves)-1] == MoveDrop {
+		moves = moves[:len(moves)-1]
+	}
+	return moves
+}
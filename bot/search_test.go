@@ -0,0 +1,73 @@
+package bot
+
+import (
+	"testing"
+
+	"tetris/engine"
+)
+
+// makes a grid with rows filled in from the bottom up, X for a taken pixel
+func gridFromRows(rows []string) Grid {
+	var grid Grid
+	for i, row := range rows {
+		for j, c := range row {
+			if c == 'X' {
+				grid[i] |= 1 << j
+			}
+		}
+	}
+	return grid
+}
+
+// do the shapes cover the same pixels, whatever order their points are in
+func sameCells(a, b engine.Shape) bool {
+	return shapeKey(a) == shapeKey(b)
+}
+
+// an overhang over the left of the floor, the only way under it is dropping an I flat in the gap and sliding it in
+var tuckRows = []string{"XX........", "XXXXXX...."}
+
+// where the I ends up tucked under the overhang
+var tucked = engine.Shape{{Row: 0, Col: 2}, {Row: 0, Col: 3}, {Row: 0, Col: 4}, {Row: 0, Col: 5}}
+
+func TestPlacementsTuck(t *testing.T) {
+	grid := gridFromRows(tuckRows)
+	var found *Placement
+	placements := grid.Placements(*engine.Tetro(4).NewTetromino())
+	for i, p := range placements {
+		if sameCells(p.Piece.Shape, tucked) {
+			found = &placements[i]
+		}
+	}
+	if found == nil {
+		t.Fatalf("none of the %d placements tuck the I under the overhang", len(placements))
+	}
+
+	// it has to get down before it can go left, a hard drop from anywhere lands on top of the overhang
+	dropped := false
+	for _, m := range found.Moves {
+		if m == MoveDrop {
+			dropped = true
+		}
+	}
+	if !dropped || found.Moves[len(found.Moves)-1] != MoveLeft {
+		t.Fatalf("the tuck is %v, want a drop and then moves left", found.Moves)
+	}
+
+	// every placement has landed and fits
+	for _, p := range placements {
+		if !grid.fits(p.Piece.Shape) || grid.dropDistance(p.Piece.Shape) != 0 {
+			t.Fatalf("placement %v is in the air or doesn't fit", p.Piece.Shape)
+		}
+	}
+}
+
+func TestPlacementsBlocked(t *testing.T) {
+	var grid Grid
+	for i := range grid {
+		grid[i] = fullRow
+	}
+	if placements := grid.Placements(*engine.Tetro(5).NewTetromino()); placements != nil {
+		t.Fatalf("a full grid has %d placements, want none", len(placements))
+	}
+}
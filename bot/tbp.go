@@ -0,0 +1,469 @@
+package bot
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+
+	"tetris/engine"
+)
+
+// External is a bot in another program that speaks the Tetris Bot Protocol (TBP) over its stdin and stdout,
+// like Cold Clear, it is told what the game looks like and suggests where to put each piece
+type External struct {
+	// what the bot said about itself when it started
+	Name, Version, Author string
+
+	// how long to wait for the bot to suggest a move before giving up on it
+	Timeout time.Duration
+
+	cmd      *exec.Cmd
+	stdin    io.WriteCloser
+	enc      *json.Encoder
+	messages chan tbpMessage
+	err      error
+
+	// where the reply to the suggestion being waited for comes, nil when the bot hasn't been asked,
+	// the bot is asked off the frame loop so a slow bot doesn't hold up the window
+	pending chan tbpReply
+
+	// what the bot thinks the game looks like, the bot is started again whenever the game stops matching it
+	started bool
+	grid    Grid
+	hold    engine.Tetro
+	queue   []engine.Tetro
+}
+
+// a message from the bot, which fields are used depends on the type
+type tbpMessage struct {
+	Type string `json:"type"`
+
+	// info
+	Name     string   `json:"name"`
+	Version  string   `json:"version"`
+	Author   string   `json:"author"`
+	Features []string `json:"features"`
+
+	// error
+	Reason string `json:"reason"`
+
+	// suggestion, the bot's favourite first
+	Moves []tbpMove `json:"moves"`
+}
+
+// the suggestion the bot sent back, or why it didn't
+type tbpReply struct {
+	suggestion tbpMessage
+	err        error
+}
+
+// a message to the bot with only a type, rules, suggest, stop and quit
+type tbpCommand struct {
+	Type string `json:"type"`
+}
+
+// tells the bot what the game looks like so it can start thinking
+type tbpStart struct {
+	Type string `json:"type"`
+
+	// the held piece, null when nothing is held
+	Hold *string `json:"hold"`
+
+	// the falling piece and then the next pieces
+	Queue []string `json:"queue"`
+
+	Combo      int  `json:"combo"`
+	BackToBack bool `json:"back_to_back"`
+
+	// 40 rows of 10 pixels from the bottom up, null for an empty pixel
+	Board [][]*string `json:"board"`
+}
+
+// tells the bot which of its moves was played
+type tbpPlay struct {
+	Type string  `json:"type"`
+	Move tbpMove `json:"move"`
+}
+
+// tells the bot about a piece that has come into view at the end of the queue
+type tbpNewPiece struct {
+	Type  string `json:"type"`
+	Piece string `json:"piece"`
+}
+
+// where a piece goes
+type tbpMove struct {
+	Location tbpLocation `json:"location"`
+
+	// none, mini or full
+	Spin string `json:"spin"`
+}
+
+// a piece, which way it faces and the pixel it rotates around
+type tbpLocation struct {
+	Type        string `json:"type"`
+	Orientation string `json:"orientation"`
+	X           int    `json:"x"`
+	Y           int    `json:"y"`
+}
+
+// how many rows TBP boards have
+const tbpBoardHeight = 40
+
+// the letter TBP uses for each tetro and for garbage
+var tbpPieces = map[engine.Pixel]string{
+	1: "O", 2: "L", 3: "J", 4: "I", 5: "T", 6: "S", 7: "Z", engine.GarbagePixel: "G",
+}
+
+// the names TBP uses for each rotation
+var tbpOrientations = []string{"north", "east", "south", "west"}
+
+// starts the bot with the command and its arguments and waits for it to say it's ready
+func StartExternal(command []string) (*External, error) {
+	if len(command) == 0 {
+		return nil, fmt.Errorf("no bot command given")
+	}
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	e, err := connect(stdin, stdout)
+	if err != nil {
+		stdin.Close()
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, err
+	}
+	e.cmd = cmd
+	return e, nil
+}
+
+// talks to a bot over the ends of its stdin and stdout and waits for it to say it's ready
+func connect(stdin io.WriteCloser, stdout io.Reader) (*External, error) {
+	e := &External{
+		Timeout:  5 * time.Second,
+		stdin:    stdin,
+		enc:      json.NewEncoder(stdin),
+		messages: make(chan tbpMessage, 16),
+	}
+	go e.read(stdout)
+
+	info, err := e.receive("info")
+	if err != nil {
+		return nil, err
+	}
+	e.Name, e.Version, e.Author = info.Name, info.Version, info.Author
+
+	if err := e.send(tbpCommand{Type: "rules"}); err != nil {
+		return nil, err
+	}
+	if _, err := e.receive("ready"); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// reads messages from the bot until it closes its stdout
+func (e *External) read(r io.Reader) {
+	defer close(e.messages)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), 1<<20)
+	for scanner.Scan() {
+		var m tbpMessage
+		if json.Unmarshal(scanner.Bytes(), &m) != nil {
+			continue
+		}
+		e.messages <- m
+	}
+}
+
+func (e *External) send(m interface{}) error {
+	if err := e.enc.Encode(m); err != nil {
+		return fmt.Errorf("talking to the bot: %w", err)
+	}
+	return nil
+}
+
+// waits for a message of the type, an error message from the bot is returned as an error
+func (e *External) receive(typ string) (tbpMessage, error) {
+	timeout := time.After(e.Timeout)
+	for {
+		select {
+		case m, ok := <-e.messages:
+			if !ok {
+				return tbpMessage{}, fmt.Errorf("the bot quit while we were waiting for %s", typ)
+			}
+			if m.Type == "error" {
+				return tbpMessage{}, fmt.Errorf("the bot said: %s", m.Reason)
+			}
+			if m.Type == typ {
+				return m, nil
+			}
+		case <-timeout:
+			return tbpMessage{}, fmt.Errorf("the bot took longer than %v to send %s", e.Timeout, typ)
+		}
+	}
+}
+
+// the falling piece and then the next pieces, as the bot should see them
+func pieces(g *engine.Game) []engine.Tetro {
+	return append([]engine.Tetro{g.CurrentPiece.Tetro}, g.PeekNext(g.QueueLength)...)
+}
+
+// does the game still look the way the bot thinks it does
+func (e *External) matches(g *engine.Game) bool {
+	if !e.started || e.grid != GridFromGame(g) || e.hold != engine.Tetro(g.HeldPiece) {
+		return false
+	}
+	queue := pieces(g)
+	if len(queue) < len(e.queue) {
+		return false
+	}
+	for i, t := range e.queue {
+		if queue[i] != t {
+			return false
+		}
+	}
+	return true
+}
+
+// the messages that tell the bot about the game from scratch, stopping what it was thinking about before
+func (e *External) start(g *engine.Game) []interface{} {
+	var messages []interface{}
+	if e.started {
+		messages = append(messages, tbpCommand{Type: "stop"})
+	}
+
+	start := tbpStart{Type: "start", Combo: g.Combo + 1, BackToBack: g.BackToBack}
+	if g.HeldPiece != 0 {
+		hold := tbpPieces[engine.Pixel(g.HeldPiece)]
+		start.Hold = &hold
+	}
+	e.queue = pieces(g)
+	for _, t := range e.queue {
+		start.Queue = append(start.Queue, tbpPieces[engine.Pixel(t)])
+	}
+	for i := 0; i < tbpBoardHeight; i++ {
+		row := make([]*string, engine.WidthOfBoardInPixels)
+		for j := range row {
+			if name, ok := tbpPieces[g.PlayingBoard.Get(engine.Point{Row: i, Col: j})]; ok {
+				row[j] = &name
+			}
+		}
+		start.Board = append(start.Board, row)
+	}
+
+	e.started = true
+	e.grid = GridFromGame(g)
+	e.hold = engine.Tetro(g.HeldPiece)
+	return append(messages, start)
+}
+
+// the pixel TBP says a piece rotates around, for I and O this is one of the four pixels around the pivot,
+// which one depends on which way the piece faces
+func tbpCenter(t engine.Tetromino) engine.Point {
+	row, col := floorHalf(t.Pivot.Row), floorHalf(t.Pivot.Col)
+	if t.Pivot.Row%2 == 0 {
+		return engine.Point{Row: row, Col: col}
+	}
+	// the offsets for north, east, south and west
+	offsets := []engine.Point{{Row: 1, Col: 0}, {Row: 1, Col: 1}, {Row: 0, Col: 1}, {Row: 0, Col: 0}}
+	if t.Tetro == 1 { // O
+		offsets = []engine.Point{{Row: 0, Col: 0}, {Row: 1, Col: 0}, {Row: 1, Col: 1}, {Row: 0, Col: 1}}
+	}
+	offset := offsets[t.Rotation]
+	return engine.Point{Row: row + offset.Row, Col: col + offset.Col}
+}
+
+// halves n rounding down, for pivots which are in half pixels
+func floorHalf(n int) int {
+	if n < 0 {
+		return -((-n + 1) / 2)
+	}
+	return n / 2
+}
+
+// the pixels a TBP location covers, or false if it isn't a tetro this game has
+func (l tbpLocation) shape() (engine.Tetro, engine.Shape, bool) {
+	var tetro engine.Tetro
+	for pixel, name := range tbpPieces {
+		if name == l.Type && pixel != engine.GarbagePixel {
+			tetro = engine.Tetro(pixel)
+		}
+	}
+	rotation := -1
+	for i, name := range tbpOrientations {
+		if name == l.Orientation {
+			rotation = i
+		}
+	}
+	if tetro == 0 || rotation == -1 {
+		return 0, nil, false
+	}
+
+	// turning the piece without kicks and then moving it so it rotates around the pixel given
+	anywhere := func(engine.Shape) bool { return true }
+	piece, _, _ := tetro.NewTetromino().Rotated(rotation, anywhere)
+	center := tbpCenter(piece)
+	return tetro, shifted(piece, l.Y-center.Row, l.X-center.Col).Shape, true
+}
+
+// finds how to get a piece to the location the bot chose, from the falling piece or by holding first
+func (e *External) placement(g *engine.Game, l tbpLocation) (Placement, bool) {
+	tetro, target, ok := l.shape()
+	if !ok {
+		return Placement{}, false
+	}
+
+	start, hold := *g.CurrentPiece, false
+	if tetro != g.CurrentPiece.Tetro {
+		held := engine.Tetro(g.HeldPiece)
+		if held == 0 {
+			held = g.PeekNext(1)[0]
+		}
+		if !g.CanHold || held != tetro {
+			return Placement{}, false
+		}
+		start, hold = *tetro.NewTetromino(), true
+	}
+
+	key := shapeKey(target)
+	for _, p := range e.grid.Placements(start) {
+		if shapeKey(p.Piece.Shape) == key {
+			p.Hold = hold
+			return p, true
+		}
+	}
+	return Placement{}, false
+}
+
+// asks the bot where to put the falling piece and puts it there once it answers,
+// returns false if there was nothing to place, the bot is still thinking or it couldn't be asked, Err says why it couldn't,
+// which it does for good if the game isn't one CheckGame allows or none of the bot's moves can be reached
+func (e *External) Play(g *engine.Game) bool {
+	if e.err != nil || g.CurrentPiece == nil {
+		return false
+	}
+	if e.pending == nil {
+		e.ask(g)
+		return false
+	}
+
+	var reply tbpReply
+	select {
+	case reply = <-e.pending:
+		e.pending = nil
+	default:
+		return false
+	}
+	if reply.err != nil {
+		e.err = reply.err
+		return false
+	}
+	// the piece may have locked or garbage come in while the bot was thinking, then it's told about the game again
+	if !e.matches(g) {
+		return false
+	}
+
+	for _, move := range reply.suggestion.Moves {
+		p, ok := e.placement(g, move.Location)
+		if !ok {
+			continue
+		}
+		if e.err = e.send(tbpPlay{Type: "play", Move: move}); e.err != nil {
+			return false
+		}
+		e.played(p)
+		Apply(g, p)
+		return true
+	}
+	e.err = fmt.Errorf("none of the bot's %d moves for %s can be reached with our rotation system",
+		len(reply.suggestion.Moves), tbpPieces[engine.Pixel(g.CurrentPiece.Tetro)])
+	return false
+}
+
+// catches the bot up on the game and asks it for a suggestion, which is sent and waited for in the background
+func (e *External) ask(g *engine.Game) {
+	if e.err = CheckGame(g); e.err != nil {
+		return
+	}
+	var messages []interface{}
+	if !e.matches(g) {
+		messages = e.start(g)
+	}
+	// telling the bot about every piece that has come into view since it last played
+	for _, t := range pieces(g)[len(e.queue):] {
+		messages = append(messages, tbpNewPiece{Type: "new_piece", Piece: tbpPieces[engine.Pixel(t)]})
+		e.queue = append(e.queue, t)
+	}
+	messages = append(messages, tbpCommand{Type: "suggest"})
+
+	reply := make(chan tbpReply, 1)
+	e.pending = reply
+	go func() {
+		for _, m := range messages {
+			if err := e.send(m); err != nil {
+				reply <- tbpReply{err: err}
+				return
+			}
+		}
+		suggestion, err := e.receive("suggestion")
+		reply <- tbpReply{suggestion, err}
+	}()
+}
+
+// updates what the bot thinks the game looks like after the placement, the same way TBP says the bot does
+func (e *External) played(p Placement) {
+	if p.Hold {
+		if e.hold == 0 {
+			e.hold = e.queue[0]
+			e.queue = e.queue[1:]
+		} else {
+			e.hold, e.queue[0] = e.queue[0], e.hold
+		}
+	}
+	e.queue = e.queue[1:]
+	e.grid, _, _ = e.grid.place(p.Piece.Shape)
+}
+
+// why the bot stopped playing, nil if it hasn't
+func (e *External) Err() error {
+	return e.err
+}
+
+// tells the bot to quit and waits for it to, killing it if it takes too long
+func (e *External) Close() error {
+	// the bot can't be talked to while a suggestion is still being asked for
+	if e.pending != nil {
+		<-e.pending
+		e.pending = nil
+	}
+	e.send(tbpCommand{Type: "quit"})
+	e.stdin.Close()
+	if e.cmd == nil {
+		return nil
+	}
+	done := make(chan error, 1)
+	go func() { done <- e.cmd.Wait() }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(e.Timeout):
+		e.cmd.Process.Kill()
+		return <-done
+	}
+}
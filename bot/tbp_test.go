@@ -0,0 +1,214 @@
+package bot
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"tetris/engine"
+)
+
+// makes a shape from row and column pairs
+func cellsAt(rowCols ...int) engine.Shape {
+	var s engine.Shape
+	for i := 0; i < len(rowCols); i += 2 {
+		s = append(s, engine.Point{Row: rowCols[i], Col: rowCols[i+1]})
+	}
+	return s
+}
+
+func TestTBPLocation(t *testing.T) {
+	tests := []struct {
+		location tbpLocation
+		want     engine.Shape
+	}{
+		{tbpLocation{"T", "north", 4, 0}, cellsAt(0, 3, 0, 4, 0, 5, 1, 4)},
+		{tbpLocation{"T", "west", 4, 1}, cellsAt(0, 4, 1, 4, 2, 4, 1, 3)},
+		{tbpLocation{"I", "north", 4, 0}, cellsAt(0, 3, 0, 4, 0, 5, 0, 6)},
+		{tbpLocation{"I", "east", 4, 2}, cellsAt(0, 4, 1, 4, 2, 4, 3, 4)},
+		{tbpLocation{"I", "south", 4, 0}, cellsAt(0, 2, 0, 3, 0, 4, 0, 5)},
+		{tbpLocation{"I", "west", 4, 1}, cellsAt(0, 4, 1, 4, 2, 4, 3, 4)},
+		{tbpLocation{"O", "north", 4, 0}, cellsAt(0, 4, 0, 5, 1, 4, 1, 5)},
+		{tbpLocation{"O", "south", 5, 1}, cellsAt(0, 4, 0, 5, 1, 4, 1, 5)},
+		{tbpLocation{"S", "north", 4, 0}, cellsAt(0, 3, 0, 4, 1, 4, 1, 5)},
+	}
+	for _, test := range tests {
+		_, shape, ok := test.location.shape()
+		if !ok || !sameCells(shape, test.want) {
+			t.Errorf("%v covers %v, want %v", test.location, shape, test.want)
+		}
+	}
+
+	if _, _, ok := (tbpLocation{"X", "north", 4, 0}).shape(); ok {
+		t.Error("a piece TBP doesn't have was turned into a shape")
+	}
+}
+
+// a bot on the other end of a pair of pipes, it keeps track of the queue and hold the way TBP says
+// and suggests putting each piece flat on the floor a bit further right than the last, holding first when hold is set
+type stubBot struct {
+	t    *testing.T
+	in   *bufio.Scanner
+	out  *json.Encoder
+	hold bool
+
+	// what the stub has been told
+	starts int
+	plays  int
+	queue  []string
+	held   string
+}
+
+func (s *stubBot) run() {
+	s.out.Encode(map[string]string{"type": "info", "name": "stub"})
+	for s.in.Scan() {
+		var m struct {
+			Type  string  `json:"type"`
+			Hold  *string `json:"hold"`
+			Queue []string
+			Piece string
+			Move  tbpMove
+		}
+		if err := json.Unmarshal(s.in.Bytes(), &m); err != nil {
+			s.t.Errorf("the stub got %s: %v", s.in.Bytes(), err)
+			return
+		}
+		switch m.Type {
+		case "rules":
+			s.out.Encode(map[string]string{"type": "ready"})
+		case "start":
+			s.starts++
+			s.queue, s.held = m.Queue, ""
+			if m.Hold != nil {
+				s.held = *m.Hold
+			}
+		case "new_piece":
+			s.queue = append(s.queue, m.Piece)
+		case "suggest":
+			piece := s.queue[0]
+			if s.hold {
+				piece = s.held
+				if piece == "" {
+					piece = s.queue[1]
+				}
+			}
+			columns := []int{1, 5, 8}
+			move := tbpMove{Location: tbpLocation{piece, "north", columns[s.plays%len(columns)], 0}, Spin: "none"}
+			s.out.Encode(map[string]interface{}{"type": "suggestion", "moves": []tbpMove{move}})
+		case "play":
+			s.plays++
+			if m.Move.Location.Type != s.queue[0] {
+				if s.held == "" {
+					s.held, s.queue = s.queue[0], s.queue[1:]
+				} else {
+					s.held, s.queue[0] = s.queue[0], s.held
+				}
+			}
+			s.queue = s.queue[1:]
+		case "quit":
+			return
+		}
+	}
+}
+
+// waits for the external bot to play the falling piece
+func playExternal(t *testing.T, e *External, g *engine.Game) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !e.Play(g) {
+		if e.Err() != nil {
+			t.Fatal(e.Err())
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the bot never played")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestExternal(t *testing.T) {
+	toBot, fromUs := io.Pipe()
+	fromBot, toUs := io.Pipe()
+	stub := &stubBot{t: t, in: bufio.NewScanner(toBot), out: json.NewEncoder(toUs)}
+	done := make(chan bool)
+	go func() {
+		stub.run()
+		toUs.Close()
+		close(done)
+	}()
+
+	e, err := connect(fromUs, fromBot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Name != "stub" {
+		t.Fatalf("the bot is called %q, want stub", e.Name)
+	}
+
+	g := engine.NewGame(1, engine.NewBagRandomizer(1, 1))
+	g.SetNextTetroFromBag()
+
+	// asking doesn't wait for the answer
+	if e.Play(&g) {
+		t.Fatal("the piece was placed before the bot could answer")
+	}
+	first := g.CurrentPiece.Tetro
+	playExternal(t, e, &g)
+	if g.PiecesPlaced != 1 || g.PlayingBoard.Get(engine.Point{Row: 0, Col: 1}) != engine.Pixel(first) {
+		t.Fatalf("the first piece wasn't placed where the bot said")
+	}
+
+	// holding, and then playing the piece that comes out of hold, the stub has to stay in step with the game
+	stub.hold = true
+	current := g.CurrentPiece.Tetro
+	playExternal(t, e, &g)
+	if engine.Tetro(g.HeldPiece) != current || g.PiecesPlaced != 2 {
+		t.Fatalf("after holding %d is held and %d pieces placed, want %d held and 2 placed", g.HeldPiece, g.PiecesPlaced, current)
+	}
+	stub.hold = false
+	playExternal(t, e, &g)
+
+	if !e.matches(&g) {
+		t.Fatalf("after playing the bot thinks the queue is %v holding %d, the game has %v holding %d",
+			e.queue, e.hold, pieces(&g), g.HeldPiece)
+	}
+
+	e.Close()
+	<-done
+	if stub.starts != 1 {
+		t.Fatalf("the bot was started %d times, once should be enough when it stays in step", stub.starts)
+	}
+}
+
+func TestExternalUnreachable(t *testing.T) {
+	toBot, fromUs := io.Pipe()
+	fromBot, toUs := io.Pipe()
+	stub := &stubBot{t: t, in: bufio.NewScanner(toBot), out: json.NewEncoder(toUs)}
+	go stub.run()
+
+	e, err := connect(fromUs, fromBot)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the floor where the stub puts the first piece is covered, so its move can't be reached
+	g := engine.NewGame(1, engine.NewBagRandomizer(1, 1))
+	g.SetNextTetroFromBag()
+	g.PlayingBoard.Set(engine.Point{Row: 0, Col: 1}, engine.GarbagePixel)
+	deadline := time.Now().Add(time.Second)
+	for e.Err() == nil && time.Now().Before(deadline) {
+		if e.Play(&g) {
+			t.Fatal("a move that can't be reached was played")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if e.Err() == nil {
+		t.Fatal("no error for a move that can't be reached")
+	}
+	if g.PiecesPlaced != 0 {
+		t.Fatalf("%d pieces were placed, the piece should have been left alone", g.PiecesPlaced)
+	}
+	e.Close()
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"tetris/bot"
+	"tetris/engine"
+)
+
+// something that places the falling piece, the built in bot or an external one
+type piecePlacer interface {
+	Play(g *engine.Game) bool
+}
+
+// botPlayer plays a game in the window by itself, placing a piece at a time at the speed given with -bot-pps
+type botPlayer struct {
+	bot  piecePlacer
+	game *engine.Game
+
+	// how long until the bot places the next piece
+	wait time.Duration
+
+	// set once an external bot has stopped working, the game carries on without it
+	stopped bool
+}
+
+// makes a bot to play the game, the external one given with -bot-command,
+// or the built in one with the weights given with -bot-weights
+func new_bot_player(game *engine.Game) (*botPlayer, error) {
+	if err := bot.CheckGame(game); err != nil {
+		return nil, err
+	}
+	if *bot_command_flag != "" {
+		external, err := bot.StartExternal(strings.Fields(*bot_command_flag))
+		if err != nil {
+			return nil, fmt.Errorf("starting %s: %w", *bot_command_flag, err)
+		}
+		return &botPlayer{bot: external, game: game}, nil
+	}
+
+	weights := bot.DefaultWeights()
+	if *bot_weights_flag != "" {
+		var err error
+		if weights, err = bot.LoadWeights(*bot_weights_flag); err != nil {
+			return nil, err
+		}
+	}
+	return &botPlayer{bot: bot.New(weights), game: game}, nil
+}
+
+// how long the bot waits between pieces
+func (p *botPlayer) interval() time.Duration {
+	return time.Duration(float64(time.Second) / *bot_pps_flag)
+}
+
+// places the falling piece once the bot has waited long enough, the game still has to be stepped for gravity and spawning
+func (p *botPlayer) think(dt time.Duration) {
+	if p.stopped || p.game.Paused || p.game.GameOver || p.game.Finished || p.game.CurrentPiece == nil {
+		return
+	}
+	p.wait -= dt
+	if p.wait > 0 {
+		return
+	}
+	if !p.bot.Play(p.game) {
+		if external, ok := p.bot.(*bot.External); ok && external.Err() != nil {
+			fmt.Fprintln(os.Stderr, "bot stopped playing:", external.Err())
+			p.stopped = true
+		}
+		// an external bot that is still thinking is asked again next frame
+		return
+	}
+
+	// a slow frame shouldn't make the bot rush the pieces after it
+	p.wait += p.interval()
+	if p.wait < 0 {
+		p.wait = 0
+	}
+}
+
+// stops an external bot when the window closes
+func (p *botPlayer) close() {
+	if external, ok := p.bot.(*bot.External); ok {
+		external.Close()
+	}
+}
@@ -0,0 +1,185 @@
+// Command tetris-bench plays lots of games with the bot and no window, to see how well a set of weights does
+// and to tune them. Each game is played to a number of pieces so a good bot doesn't play forever,
+// and the same seeds are used every run so two sets of weights can be compared fairly.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"tetris/bot"
+	"tetris/engine"
+)
+
+var (
+	// how many games to play
+	games_flag = flag.Int("games", 1000, "how many games to play")
+
+	// how many pieces each game is played to
+	pieces_flag = flag.Int("pieces", 500, "how many pieces each game is played to, unless it tops out first")
+
+	// the seed of the first game, each game after uses the next seed
+	seed_flag = flag.Int64("seed", 1, "seed of the first game, the others use the seeds after it")
+
+	// a JSON file with the weights to play with
+	weights_flag = flag.String("weights", "", "JSON file with the bot's weights (default the built in ones)")
+
+	// think about the next piece too
+	lookahead_flag = flag.Bool("lookahead", false, "score each placement together with the best place for the next piece")
+
+	// how many rounds of tuning to do, 0 to just play
+	tune_flag = flag.Int("tune", 0, "rounds of tuning, each nudges the weights and keeps them if more lines are cleared")
+
+	// where to write the tuned weights
+	out_flag = flag.String("out", "", "file to write the tuned weights to as JSON (default stdout)")
+
+	// how many games to play at once
+	workers_flag = flag.Int("workers", runtime.NumCPU(), "how many games to play at the same time")
+)
+
+// how a set of weights did over every game
+type result struct {
+	games  int
+	lines  int
+	score  int
+	pieces int
+	topped int
+}
+
+func (r result) String() string {
+	n := float64(r.games)
+	return fmt.Sprintf("%d games, %.1f lines, %.0f score, %.1f pieces on average, %d topped out",
+		r.games, float64(r.lines)/n, float64(r.score)/n, float64(r.pieces)/n, r.topped)
+}
+
+// plays one game with the bot until it tops out or has placed the pieces
+func play(b *bot.Bot, seed int64, pieces int) *engine.Game {
+	game := engine.NewGame(seed, engine.NewBagRandomizer(seed, 1))
+	game.SetNextTetroFromBag()
+	for !game.GameOver && game.PiecesPlaced < pieces {
+		if !b.Play(&game) {
+			break
+		}
+	}
+	return &game
+}
+
+// plays every game with the weights, spread over the workers
+func bench(w bot.Weights, lookahead bool) result {
+	seeds := make(chan int64)
+	go func() {
+		for i := 0; i < *games_flag; i++ {
+			seeds <- *seed_flag + int64(i)
+		}
+		close(seeds)
+	}()
+
+	var (
+		mu    sync.Mutex
+		total result
+		wg    sync.WaitGroup
+	)
+	for i := 0; i < *workers_flag; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b := &bot.Bot{Weights: w, Lookahead: lookahead}
+			for seed := range seeds {
+				g := play(b, seed, *pieces_flag)
+				mu.Lock()
+				total.games++
+				total.lines += g.LinesCleared
+				total.score += g.Score
+				total.pieces += g.PiecesPlaced
+				if g.GameOver || g.PiecesPlaced < *pieces_flag {
+					total.topped++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return total
+}
+
+// nudges every weight by a random amount up to step times its size
+func nudge(w bot.Weights, rng *rand.Rand, step float64) bot.Weights {
+	for _, f := range []*float64{
+		&w.LandingHeight, &w.ErodedCells, &w.LinesCleared, &w.RowTransitions, &w.ColumnTransitions,
+		&w.Holes, &w.Wells, &w.AggregateHeight, &w.Bumpiness,
+	} {
+		size := *f
+		if size < 0 {
+			size = -size
+		}
+		if size < 1 {
+			size = 1
+		}
+		*f += rng.NormFloat64() * step * size
+	}
+	return w
+}
+
+// loads the weights from the file given with -weights, or the built in ones
+func load_weights() (bot.Weights, error) {
+	if *weights_flag == "" {
+		return bot.DefaultWeights(), nil
+	}
+	return bot.LoadWeights(*weights_flag)
+}
+
+func main() {
+	flag.Parse()
+	if *games_flag <= 0 || *pieces_flag <= 0 || *workers_flag <= 0 {
+		fmt.Fprintln(os.Stderr, "-games, -pieces and -workers must be more than 0")
+		os.Exit(2)
+	}
+
+	w, err := load_weights()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	start := time.Now()
+	best := bench(w, *lookahead_flag)
+	fmt.Fprintf(os.Stderr, "%v in %v\n", best, time.Since(start).Round(time.Millisecond))
+	if *tune_flag <= 0 {
+		return
+	}
+
+	// a simple hill climb, the nudges get smaller as the tuning goes on
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for round := 1; round <= *tune_flag; round++ {
+		step := 0.3 * (1 - float64(round-1)/float64(*tune_flag))
+		candidate := nudge(w, rng, step)
+		r := bench(candidate, *lookahead_flag)
+		kept := ""
+		if r.lines > best.lines {
+			w, best = candidate, r
+			kept = ", kept"
+		}
+		fmt.Fprintf(os.Stderr, "round %d: %v%s\n", round, r, kept)
+	}
+
+	data, err := json.MarshalIndent(w, "", "  ")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	data = append(data, '\n')
+	if *out_flag == "" {
+		os.Stdout.Write(data)
+		return
+	}
+	if err := os.WriteFile(*out_flag, data, 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
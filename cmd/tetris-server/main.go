@@ -0,0 +1,40 @@
+// Command tetris-server hosts versus matches for games on the same network.
+// Players join a room with tetris -connect, the match starts once everyone in the room is ready,
+// and anyone can watch a room with tetris -connect -spectate.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+
+	"tetris/netplay"
+)
+
+var (
+	// where to listen for games
+	addr_flag = flag.String("addr", fmt.Sprintf(":%d", netplay.DefaultPort), "address to listen on")
+
+	// how many seconds to count down before a match
+	countdown_flag = flag.Int("countdown", 3, "seconds to count down before a match starts")
+)
+
+func main() {
+	flag.Parse()
+
+	l, err := net.Listen("tcp", *addr_flag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	server := netplay.NewServer()
+	server.CountdownSeconds = *countdown_flag
+	server.Log = log.New(os.Stderr, "", log.LstdFlags)
+	server.Log.Printf("listening on %s, protocol version %d", l.Addr(), netplay.ProtocolVersion)
+	if err := server.Serve(l); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,143 @@
+// Command tetris-tui plays the game in a terminal, for when there's no display to open a window on.
+// It uses the same engine as the window version so the rules are exactly the same.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"tetris/engine"
+)
+
+// how often the game is stepped and drawn
+const FrameTime = time.Second / 60
+
+var (
+	// the seed for the randomizer, two games with the same seed get the same tetros in the same order
+	seed_flag = flag.Int64("seed", 0, "seed for the piece randomizer, 0 picks one from the current time")
+
+	// which randomizer picks the tetros
+	randomizer_flag = flag.String("randomizer", "7bag", "piece randomizer, one of: "+strings.Join(engine.RandomizerNames, ", "))
+
+	// the rules to play by
+	mode_flag = flag.String("mode", "marathon", "game mode, one of: "+strings.Join(engine.ModeNames, ", "))
+)
+
+// gets the field of the input that a key controls, or nil if the key doesn't control anything
+func input_field(in *engine.Input, k key) *bool {
+	switch k {
+	case KeyLeft:
+		return &in.Left
+	case KeyRight:
+		return &in.Right
+	case KeyDown:
+		return &in.SoftDrop
+	case ' ':
+		return &in.HardDrop
+	case KeyUp, 'x', 'X':
+		return &in.RotateClockWise
+	case 'z', 'Z':
+		return &in.RotateCounterClockWise
+	case 'a', 'A':
+		return &in.Rotate180
+	case 'c', 'C':
+		return &in.Hold
+	}
+	return nil
+}
+
+func main() {
+	flag.Parse()
+
+	seed := *seed_flag
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	randomizer, err := engine.NewRandomizer(*randomizer_flag, seed, engine.Tetrominoes.Len())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	mode, err := engine.NewMode(*mode_flag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	restore, err := make_raw()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "putting the terminal in raw mode:", err)
+		os.Exit(1)
+	}
+	defer restore()
+	// hiding the cursor and clearing the screen, then showing the cursor again when we're done
+	fmt.Print("\x1b[?25l\x1b[2J")
+	defer fmt.Print("\x1b[?25h\r\n")
+
+	game := engine.NewGame(seed, randomizer)
+	game.SetMode(mode)
+
+	// a rotates 180, so finesse can count on it
+	game.Finesse180 = true
+	game.SetNextTetroFromBag()
+
+	keys := make(chan key, 64)
+	go read_keys(keys)
+
+	ticker := time.NewTicker(FrameTime)
+	defer ticker.Stop()
+
+	var (
+		// keys that have been pressed but not passed to the game yet
+		pending []key
+
+		// the input passed to the game on the last frame
+		last_input engine.Input
+
+		// when the last frame was
+		last_frame = time.Now()
+	)
+
+	for {
+		select {
+		case k, ok := <-keys:
+			if !ok || k == 'q' || k == 'Q' || k == KeyCtrlC {
+				return
+			}
+			if k == 'p' || k == 'P' {
+				game.Paused = !game.Paused
+				continue
+			}
+			pending = append(pending, k)
+
+		case <-ticker.C:
+			// terminals only tell us when a key is pressed, never when it's let go,
+			// so each press holds its key for a single frame and the terminal's own key repeat does the rest,
+			// a key that was held on the last frame has to wait a frame so the game sees it being pressed again
+			var in engine.Input
+			var later []key
+			for _, k := range pending {
+				field := input_field(&in, k)
+				if field == nil {
+					continue
+				}
+				if *field || *input_field(&last_input, k) {
+					later = append(later, k)
+					continue
+				}
+				*field = true
+			}
+			pending = later
+
+			dt := time.Since(last_frame)
+			last_frame = time.Now()
+			game.Step(dt, in)
+			last_input = in
+
+			os.Stdout.WriteString(render(&game))
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+
+	"tetris/engine"
+	"tetris/scores"
+)
+
+const (
+	// how many characters wide the panel on the left of the board is
+	LeftPanelWidth = 12
+
+	// each pixel of the board is drawn as this many characters, so the pixels come out roughly square
+	CellWidth = 2
+)
+
+// the escape sequence that sets the background to a colour
+func background(c color.RGBA) string {
+	return fmt.Sprintf("\x1b[48;2;%d;%d;%dm", c.R, c.G, c.B)
+}
+
+// draws a single pixel of a colour
+func cell(c color.RGBA) string {
+	return background(c) + strings.Repeat(" ", CellWidth) + "\x1b[0m"
+}
+
+// draws a piece the way it looks when it spawns, as 4 pixels wide lines, one for each row of the tallest piece of the set,
+// pieces wider than 4 pixels are cut off
+func preview(pieces *engine.PieceSet, t engine.Tetro) []string {
+	lines := make([]string, pieces.MaxHeight())
+	var shape engine.Shape
+	if t != 0 {
+		shape, _, _ = pieces.Preview(t)
+	}
+	for row := len(lines) - 1; row >= 0; row-- {
+		for col := 0; col < 4; col++ {
+			if engine.ContainsShape(shape, &engine.Point{Row: row, Col: col}) {
+				lines[len(lines)-1-row] += cell(pieces.Color(engine.Pixel(t)))
+			} else {
+				lines[len(lines)-1-row] += strings.Repeat(" ", CellWidth)
+			}
+		}
+	}
+	return lines
+}
+
+// draws the whole game, the held tetro on the left, the board in the middle
+// and the next tetros and the score on the right
+func render(g *engine.Game) string {
+	var left, right, board []string
+
+	left = append(left, fmt.Sprintf("%-*s", LeftPanelWidth, "Hold"))
+	for _, line := range preview(g.Pieces, engine.Tetro(g.HeldPiece)) {
+		left = append(left, line+strings.Repeat(" ", LeftPanelWidth-4*CellWidth))
+	}
+
+	right = append(right, "Next")
+	for _, next := range g.PeekNext(g.QueueLength) {
+		right = append(right, preview(g.Pieces, next)...)
+		right = append(right, "")
+	}
+	right = append(right,
+		fmt.Sprintf("Score %d", g.Score),
+		fmt.Sprintf("Level %d", g.Level),
+		fmt.Sprintf("Lines %d", g.LinesCleared),
+		fmt.Sprintf("Goal  %d", g.LinesToNextLevel()),
+		"",
+		fmt.Sprintf("Fin   %.1f%%", g.FinessePercent()),
+		fmt.Sprintf("Fault %d", g.FinesseFaults),
+	)
+	if sprint, ok := g.Mode.(*engine.Sprint); ok {
+		right = append(right,
+			"",
+			fmt.Sprintf("Time  %s", scores.FormatTime(g.PlayTime)),
+			fmt.Sprintf("Left  %d", sprint.LinesLeft(g)),
+			fmt.Sprintf("PPS   %.2f", g.PiecesPerSecond()),
+		)
+		for i, split := range sprint.Splits {
+			right = append(right, fmt.Sprintf("%3d   %s", (i+1)*sprint.SplitLines, scores.FormatTime(split)))
+		}
+	}
+	if _, ok := g.Mode.(*engine.Dig); ok {
+		right = append(right,
+			"",
+			fmt.Sprintf("Time  %s", scores.FormatTime(g.PlayTime)),
+			fmt.Sprintf("Dig   %d", g.GarbageLeft()),
+		)
+	}
+	if ultra, ok := g.Mode.(*engine.Ultra); ok {
+		right = append(right, "", fmt.Sprintf("Left  %s", scores.FormatTime(ultra.TimeLeft(g))))
+	}
+
+	composed := g.ComposeBoard(true)
+	border := "+" + strings.Repeat("-", composed.Width*CellWidth) + "+"
+	board = append(board, border)
+	for i := composed.Visible - 1; i >= 0; i-- {
+		line := "|"
+		for j := 0; j < composed.Width; j++ {
+			line += cell(g.Pieces.Color(composed.Pixels[i][j]))
+		}
+		board = append(board, line+"|")
+	}
+	board = append(board, border)
+
+	var b strings.Builder
+	// moving the cursor to the top left so the frame is drawn over the last one
+	b.WriteString("\x1b[H")
+	for i, line := range board {
+		l := strings.Repeat(" ", LeftPanelWidth)
+		if i < len(left) {
+			l = left[i]
+		}
+		r := ""
+		if i < len(right) {
+			r = right[i]
+		}
+		// clearing to the end of each line gets rid of anything longer that was drawn there before
+		fmt.Fprintf(&b, "%s %s %s\x1b[K\r\n", l, line, r)
+	}
+
+	switch {
+	case g.GameOver:
+		fmt.Fprintf(&b, "GAME OVER (%s), press q to quit", g.GameOverReason)
+	case g.Finished:
+		fmt.Fprintf(&b, "FINISHED in %s with %d points, press q to quit", scores.FormatTime(g.PlayTime), g.Score)
+	case g.Paused:
+		b.WriteString("Paused, press p to carry on")
+	default:
+		b.WriteString("arrows move, space drops, z/x/a rotate, c holds, p pauses, q quits")
+	}
+	b.WriteString("\x1b[K")
+	return b.String()
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// a key read from the terminal, either a printable character or one of the keys below
+type key rune
+
+// keys that come from escape sequences rather than single characters
+const (
+	KeyUp key = -1 - iota
+	KeyDown
+	KeyRight
+	KeyLeft
+	KeyEscape
+	KeyCtrlC key = 3
+)
+
+// puts the terminal into raw mode so keys arrive as soon as they're pressed without being echoed,
+// returns a function that puts the terminal back how it was
+func make_raw() (func(), error) {
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, err
+	}
+	return func() {
+		stty(strings.TrimSpace(saved))
+	}, nil
+}
+
+// runs stty on the terminal we're attached to
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// reads keys from stdin and sends them down the channel until stdin closes
+func read_keys(keys chan<- key) {
+	r := bufio.NewReader(os.Stdin)
+	defer close(keys)
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return
+		}
+		if b != 0x1b {
+			keys <- key(b)
+			continue
+		}
+		// an escape on its own is the escape key, otherwise it starts an arrow key sequence like ESC [ A
+		if r.Buffered() == 0 {
+			keys <- KeyEscape
+			continue
+		}
+		if next, _ := r.ReadByte(); next != '[' && next != 'O' {
+			keys <- KeyEscape
+			continue
+		}
+		switch code, _ := r.ReadByte(); code {
+		case 'A':
+			keys <- KeyUp
+		case 'B':
+			keys <- KeyDown
+		case 'C':
+			keys <- KeyRight
+		case 'D':
+			keys <- KeyLeft
+		}
+	}
+}
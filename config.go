@@ -0,0 +1,285 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/faiface/pixel/pixelgl"
+
+	"tetris/engine"
+)
+
+// the actions keys can be bound to in the config file
+const (
+	ActionMoveLeft  = "move_left"
+	ActionMoveRight = "move_right"
+	ActionSoftDrop  = "soft_drop"
+	ActionHardDrop  = "hard_drop"
+	ActionRotateCW  = "rotate_cw"
+	ActionRotateCCW = "rotate_ccw"
+	ActionRotate180 = "rotate_180"
+	ActionHold      = "hold"
+	ActionPause     = "pause"
+	ActionStats     = "stats"
+)
+
+// the config file is $XDG_CONFIG_HOME/ConfigDirName/ConfigFileName
+const (
+	ConfigDirName  = "tetris"
+	ConfigFileName = "config.json"
+)
+
+// Config is what can be set in the config file, anything left out of the file keeps its default
+type Config struct {
+	// the keys bound to each action, by the names pixelgl gives them, like "Left", "Space" or "LeftShift"
+	Keys map[string][]string `json:"keys"`
+
+	// the keys the second player uses in a versus match, the pause key of the first player pauses both
+	Player2Keys map[string][]string `json:"player2_keys"`
+
+	// how much garbage each kind of line clear sends in a versus match
+	Attack engine.AttackTable `json:"attack"`
+
+	// DAS, ARR and the soft drop factor
+	Handling engine.Handling `json:"handling"`
+
+	// how many milliseconds to wait after clearing lines before the next piece appears
+	LineClearDelayMillis int `json:"line_clear_delay_millis"`
+
+	// how many milliseconds the piece can rest on the stack before it locks
+	LockDelayMillis int `json:"lock_delay_millis"`
+
+	// what resets the lock delay, "extended", "infinite" or "classic"
+	LockMode engine.LockMode `json:"lock_mode"`
+
+	// how many times moving or rotating can reset the lock delay in extended mode
+	MaxLockResets int `json:"max_lock_resets"`
+
+	// which of block out, lock out and partial lock out end the game
+	TopOut engine.TopOutRules `json:"top_out"`
+
+	// how fast pieces fall at each level, "guideline", "nes" or "20g"
+	Gravity engine.GravityCurve `json:"gravity"`
+
+	// the level the game starts at
+	StartLevel int `json:"start_level"`
+
+	// how many lines it takes to go up a level, "fixed" for 10 every level or "variable" for 5 times the level
+	LevelGoal engine.LevelGoal `json:"level_goal"`
+
+	// how many of the pieces coming next are shown
+	QueueLength int `json:"queue_length"`
+}
+
+// returns the config used when there's no config file
+func DefaultConfig() Config {
+	return Config{
+		Keys: map[string][]string{
+			ActionMoveLeft:  {"Left"},
+			ActionMoveRight: {"Right"},
+			ActionSoftDrop:  {"Down"},
+			ActionHardDrop:  {"Space"},
+			ActionRotateCW:  {"Up", "X"},
+			ActionRotateCCW: {"Z"},
+			ActionRotate180: {"A"},
+			ActionHold:      {"C", "LeftShift"},
+			ActionPause:     {"Escape"},
+			ActionStats:     {"Tab"},
+		},
+		Player2Keys: map[string][]string{
+			ActionMoveLeft:  {"J"},
+			ActionMoveRight: {"L"},
+			ActionSoftDrop:  {"K"},
+			ActionHardDrop:  {"I"},
+			ActionRotateCW:  {"O"},
+			ActionRotateCCW: {"U"},
+			ActionRotate180: {"P"},
+			ActionHold:      {"RightShift"},
+		},
+		Attack:          engine.DefaultAttackTable(),
+		Handling:        engine.DefaultHandling(),
+		LockDelayMillis: 500,
+		LockMode:        engine.LockExtended,
+		MaxLockResets:   15,
+		TopOut:          engine.DefaultTopOutRules(),
+		Gravity:         engine.GravityGuideline,
+		StartLevel:      1,
+		LevelGoal:       engine.GoalFixed,
+		QueueLength:     5,
+	}
+}
+
+// where the config file is looked for when -config isn't given
+func default_config_path() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, ConfigDirName, ConfigFileName), nil
+}
+
+// loads the config file at path on top of the defaults, a missing file just gives the defaults,
+// the key bindings are read on their own and then merged with the defaults by merge_keys
+func load_config(path string) (Config, error) {
+	cfg := DefaultConfig()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+	defaults := cfg
+	cfg.Keys, cfg.Player2Keys = nil, nil
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("reading %s: %w", path, err)
+	}
+	keys, player2_keys := cfg.Keys, cfg.Player2Keys
+	cfg.Keys = merge_keys(defaults.Keys, keys, player2_keys)
+	cfg.Player2Keys = merge_keys(defaults.Player2Keys, player2_keys, keys)
+	for _, err := range []error{cfg.LockMode.Valid(), cfg.Gravity.Valid(), cfg.LevelGoal.Valid()} {
+		if err != nil {
+			return cfg, fmt.Errorf("reading %s: %w", path, err)
+		}
+	}
+	if cfg.QueueLength < 1 {
+		return cfg, fmt.Errorf("reading %s: queue_length is %d, at least 1 piece has to be shown", path, cfg.QueueLength)
+	}
+	return cfg, nil
+}
+
+// the key bindings from the config file, with the default keys for every action the file leaves out,
+// apart from default keys the file binds to something else, for this player or the other one given in others,
+// so a key is never bound to two actions
+func merge_keys(defaults, keys, others map[string][]string) map[string][]string {
+	taken := map[string]bool{}
+	merged := map[string][]string{}
+	for action, names := range keys {
+		merged[action] = names
+		for _, name := range names {
+			taken[name] = true
+		}
+	}
+	for _, names := range others {
+		for _, name := range names {
+			taken[name] = true
+		}
+	}
+	for action, names := range defaults {
+		if _, ok := keys[action]; ok {
+			continue
+		}
+		for _, name := range names {
+			if !taken[name] {
+				merged[action] = append(merged[action], name)
+			}
+		}
+	}
+	return merged
+}
+
+// keyBindings are the buttons bound to each action
+type keyBindings map[string][]pixelgl.Button
+
+// turns the key names for each action into pixelgl buttons, failing on any name or action it doesn't know
+func bindings(keys map[string][]string) (keyBindings, error) {
+	buttons := map[string]pixelgl.Button{}
+	for b := pixelgl.Button(0); b <= pixelgl.KeyLast; b++ {
+		if name := b.String(); name != "Invalid" {
+			buttons[name] = b
+		}
+	}
+
+	actions := map[string]bool{
+		ActionMoveLeft: true, ActionMoveRight: true, ActionSoftDrop: true, ActionHardDrop: true,
+		ActionRotateCW: true, ActionRotateCCW: true, ActionRotate180: true, ActionHold: true, ActionPause: true,
+		ActionStats: true,
+	}
+
+	k := keyBindings{}
+	bound := map[string]string{}
+	for action, names := range keys {
+		if !actions[action] {
+			return nil, fmt.Errorf("unknown action %q in key bindings", action)
+		}
+		for _, name := range names {
+			b, ok := buttons[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown key %q bound to %s, expected one of %v", name, action, sorted_keys(buttons))
+			}
+			if other, ok := bound[name]; ok && other != action {
+				return nil, fmt.Errorf("key %q is bound to both %s and %s", name, other, action)
+			}
+			bound[name] = action
+			k[action] = append(k[action], b)
+		}
+	}
+	return k, nil
+}
+
+// checks no key is bound for both players, which a versus match would send to both boards
+func shared_keys(keys, player2_keys map[string][]string) error {
+	actions := map[string]string{}
+	for action, names := range keys {
+		for _, name := range names {
+			actions[name] = action
+		}
+	}
+	for action, names := range player2_keys {
+		for _, name := range names {
+			if other, ok := actions[name]; ok {
+				return fmt.Errorf("key %q is bound to %s for player 1 and %s for player 2", name, other, action)
+			}
+		}
+	}
+	return nil
+}
+
+// is any key bound to the action held down
+func (k keyBindings) pressed(win *pixelgl.Window, action string) bool {
+	for _, b := range k[action] {
+		if win.Pressed(b) {
+			return true
+		}
+	}
+	return false
+}
+
+// was any key bound to the action pressed this frame
+func (k keyBindings) just_pressed(win *pixelgl.Window, action string) bool {
+	for _, b := range k[action] {
+		if win.JustPressed(b) {
+			return true
+		}
+	}
+	return false
+}
+
+// the state of every game key this frame, to pass to Game.Step
+func (k keyBindings) input(win *pixelgl.Window) engine.Input {
+	return engine.Input{
+		Left:                   k.pressed(win, ActionMoveLeft),
+		Right:                  k.pressed(win, ActionMoveRight),
+		SoftDrop:               k.pressed(win, ActionSoftDrop),
+		HardDrop:               k.pressed(win, ActionHardDrop),
+		RotateClockWise:        k.pressed(win, ActionRotateCW),
+		RotateCounterClockWise: k.pressed(win, ActionRotateCCW),
+		Rotate180:              k.pressed(win, ActionRotate180),
+		Hold:                   k.pressed(win, ActionHold),
+	}
+}
+
+// the keys of a map in alphabetical order
+func sorted_keys(m map[string]pixelgl.Button) []string {
+	var names []string
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,79 @@
+package engine
+
+// AttackTable is how many lines of garbage each kind of line clear sends to the opponent
+type AttackTable struct {
+	Single int `json:"single"`
+	Double int `json:"double"`
+	Triple int `json:"triple"`
+	Tetris int `json:"tetris"`
+
+	TSpinMiniSingle int `json:"tspin_mini_single"`
+	TSpinMiniDouble int `json:"tspin_mini_double"`
+	TSpinSingle     int `json:"tspin_single"`
+	TSpinDouble     int `json:"tspin_double"`
+	TSpinTriple     int `json:"tspin_triple"`
+
+	// extra lines sent for a back-to-back tetris or t-spin
+	BackToBack int `json:"back_to_back"`
+
+	// extra lines sent for each combo count, starting at a combo of 1,
+	// combos longer than the table send the last entry
+	Combo []int `json:"combo"`
+
+	// lines sent for clearing the whole board, on top of the clear itself
+	PerfectClear int `json:"perfect_clear"`
+}
+
+// returns the attack table from the guideline versus games
+func DefaultAttackTable() AttackTable {
+	return AttackTable{
+		Single:          0,
+		Double:          1,
+		Triple:          2,
+		Tetris:          4,
+		TSpinMiniSingle: 0,
+		TSpinMiniDouble: 1,
+		TSpinSingle:     2,
+		TSpinDouble:     4,
+		TSpinTriple:     6,
+		BackToBack:      1,
+		Combo:           []int{1, 1, 2, 2, 3, 3, 4, 4, 4, 5},
+		PerfectClear:    10,
+	}
+}
+
+// how many lines of garbage a lock sends
+func (t AttackTable) Attack(e ClearEvent) int {
+	if e.Lines == 0 {
+		return 0
+	}
+
+	var lines int
+	switch e.TSpin {
+	case TSpinMini:
+		lines = capped([]int{0, t.TSpinMiniSingle, t.TSpinMiniDouble}, e.Lines)
+	case TSpinFull:
+		lines = capped([]int{0, t.TSpinSingle, t.TSpinDouble, t.TSpinTriple}, e.Lines)
+	default:
+		lines = capped([]int{0, t.Single, t.Double, t.Triple, t.Tetris}, e.Lines)
+	}
+
+	if e.BackToBack {
+		lines += t.BackToBack
+	}
+	if e.Combo > 0 && len(t.Combo) > 0 {
+		lines += capped(t.Combo, e.Combo-1)
+	}
+	if e.PerfectClear {
+		lines += t.PerfectClear
+	}
+	return lines
+}
+
+// gets table[i], or the last entry if i is past the end of the table
+func capped(table []int, i int) int {
+	if i >= len(table) {
+		return table[len(table)-1]
+	}
+	return table[i]
+}
@@ -0,0 +1,40 @@
+package engine
+
+import "testing"
+
+func TestAttack(t *testing.T) {
+	table := DefaultAttackTable()
+	tests := []struct {
+		name  string
+		event ClearEvent
+		want  int
+	}{
+		{"nothing", ClearEvent{}, 0},
+		{"single", ClearEvent{Lines: 1}, 0},
+		{"double", ClearEvent{Lines: 2}, 1},
+		{"triple", ClearEvent{Lines: 3}, 2},
+		{"tetris", ClearEvent{Lines: 4}, 4},
+		{"5 lines send as much as a tetris", ClearEvent{Lines: 5}, 4},
+		{"t-spin mini without a clear", ClearEvent{TSpin: TSpinMini}, 0},
+		{"t-spin mini single", ClearEvent{Lines: 1, TSpin: TSpinMini}, 0},
+		{"t-spin mini double", ClearEvent{Lines: 2, TSpin: TSpinMini}, 1},
+		{"t-spin single", ClearEvent{Lines: 1, TSpin: TSpinFull}, 2},
+		{"t-spin double", ClearEvent{Lines: 2, TSpin: TSpinFull}, 4},
+		{"t-spin triple", ClearEvent{Lines: 3, TSpin: TSpinFull}, 6},
+		{"back-to-back tetris", ClearEvent{Lines: 4, BackToBack: true}, 5},
+		{"back-to-back t-spin double", ClearEvent{Lines: 2, TSpin: TSpinFull, BackToBack: true}, 5},
+		{"single on a 1 combo", ClearEvent{Lines: 1, Combo: 1}, 1},
+		{"double on a 5 combo", ClearEvent{Lines: 2, Combo: 5}, 1 + 3},
+		{"combo past the end of the table", ClearEvent{Lines: 1, Combo: 30}, 5},
+		{"perfect clear", ClearEvent{Lines: 2, PerfectClear: true}, 1 + 10},
+	}
+	for _, tc := range tests {
+		if got := table.Attack(tc.event); got != tc.want {
+			t.Errorf("%s sends %d lines, want %d", tc.name, got, tc.want)
+		}
+	}
+
+	if got := (AttackTable{Tetris: 4}).Attack(ClearEvent{Lines: 4, Combo: 3}); got != 4 {
+		t.Errorf("a table without combos sends %d lines for a tetris on a 3 combo, want 4", got)
+	}
+}
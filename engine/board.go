@@ -0,0 +1,244 @@
+package engine
+
+import (
+	"fmt"
+	"image/color"
+)
+
+// the size of the standard board, other sizes can be chosen when a game is made with Game.SetBoardSize
+const (
+	// How many pixels wide is the board.
+	// pixels being the pixels defined in this game, not screen pixels
+	WidthOfBoardInPixels = 10
+
+	// How many pixels tall is the board.
+	// pixels being the pixels defined in this game, not screen pixels
+	HeightOfBoardInPixels = 24
+
+	// How many pixels tall is the non-hidden part of the board.
+	// pixels being the pixels defined in this game, not screen pixels
+	NonHiddenPixelHeight = 20
+
+	// how many rows there are above the visible part of the board, where pieces spawn
+	HiddenRows = HeightOfBoardInPixels - NonHiddenPixelHeight
+
+	// the widest a board can be, so each row fits in a bitmask
+	MaxBoardWidth = 16
+
+	// the narrowest board a game can be played on, a garbage row needs a pixel of garbage next to its hole
+	MinBoardWidth = 2
+)
+
+type Point struct {
+	Row, Col int // y, x
+}
+
+// a Pixel is an integer signifying what color the pixel on the board should be
+type Pixel int
+
+// a Tetro or otherwise known as a tetromino are the pieces that are dropped from the top of the board,
+// the int value is which piece of the game's piece set it is, starting at 1
+type Tetro int
+
+// types of Tetrominos in the standard set:
+// O: 1
+// L: 2
+// J: 3
+// I: 4
+// T: 5
+// S: 6
+// Z: 7
+
+// a shape is a list of points, usually 4, that makes up a piece
+type Shape []Point
+
+type Tetromino struct {
+	Shape Shape
+	Tetro Tetro
+
+	// which way the tetro is facing, it starts in RotationSpawn
+	Rotation Rotation
+
+	// the point the tetro rotates around, this is in half pixels (both coordinates are doubled)
+	// because the I and O tetros rotate around the corner between pixels rather than the middle of one
+	Pivot Point
+
+	// which wall kicks it uses, from its piece set
+	Kicks string
+}
+
+// the playing board, a row of pixels for each row from the bottom up along with a bitmask of which of them are taken,
+// so checking if a piece fits or if a row is full doesn't have to look at every pixel
+type Board struct {
+	// how many pixels wide the board is, at most MaxBoardWidth
+	Width int
+
+	// how many rows the board has, counting the hidden ones above the visible part
+	Height int
+
+	// how many rows from the bottom can be seen
+	Visible int
+
+	// bit j of a row is set when the pixel in column j isn't empty
+	Rows []uint16
+
+	// the color of every pixel, empty pixels are 0
+	Pixels [][]Pixel
+}
+
+// returns a new empty board with visible rows that can be seen and HiddenRows more above them,
+// the width can't be more than MaxBoardWidth
+func NewBoard(width, visible int) Board {
+	if width < 1 || width > MaxBoardWidth || visible < 1 {
+		panic(fmt.Sprintf("Invalid board size passed into NewBoard: %vx%v", width, visible))
+	}
+	b := Board{
+		Width:   width,
+		Height:  visible + HiddenRows,
+		Visible: visible,
+	}
+	b.Rows = make([]uint16, b.Height)
+	b.Pixels = make([][]Pixel, b.Height)
+	for i := range b.Pixels {
+		b.Pixels[i] = make([]Pixel, width)
+	}
+	return b
+}
+
+// checks that a board can be made width pixels wide with visible rows that can be seen,
+// and that every piece of the set fits in it where it spawns
+func CheckBoardSize(width, visible int, pieces *PieceSet) error {
+	if width < MinBoardWidth || width > MaxBoardWidth {
+		return fmt.Errorf("the board can't be %d pixels wide, it has to be between %d and %d", width, MinBoardWidth, MaxBoardWidth)
+	}
+	if visible < 1 {
+		return fmt.Errorf("the board can't be %d pixels tall", visible)
+	}
+	for _, p := range pieces.Pieces {
+		if p.width > width || p.height > visible+HiddenRows {
+			return fmt.Errorf("piece %s of %s doesn't fit on a %dx%d board", p.Name, pieces.Name, width, visible)
+		}
+	}
+	return nil
+}
+
+// returns a new empty board the standard size
+func NewStandardBoard() Board {
+	return NewBoard(WidthOfBoardInPixels, NonHiddenPixelHeight)
+}
+
+// returns a copy of the board that can be changed without changing this one
+func (b *Board) Clone() Board {
+	c := *b
+	c.Rows = append([]uint16(nil), b.Rows...)
+	c.Pixels = make([][]Pixel, len(b.Pixels))
+	for i, row := range b.Pixels {
+		c.Pixels[i] = append([]Pixel(nil), row...)
+	}
+	return c
+}
+
+// a row with every pixel taken
+func (b *Board) fullRow() uint16 {
+	return 1<<b.Width - 1
+}
+
+// is the point on the board
+func (b *Board) inside(p Point) bool {
+	return p.Row >= 0 && p.Row < b.Height && p.Col >= 0 && p.Col < b.Width
+}
+
+// gets the pixel at a point, anything off the board is empty
+func (b *Board) Get(p Point) Pixel {
+	if !b.inside(p) {
+		return Pixel(0)
+	}
+	return b.Pixels[p.Row][p.Col]
+}
+
+// sets the pixel at a point, points off the board are ignored
+func (b *Board) Set(p Point, pixel Pixel) {
+	if !b.inside(p) {
+		return
+	}
+	b.Pixels[p.Row][p.Col] = pixel
+	if pixel == Pixel(0) {
+		b.Rows[p.Row] &^= 1 << p.Col
+	} else {
+		b.Rows[p.Row] |= 1 << p.Col
+	}
+}
+
+// is the pixel at a point taken, the walls and floor and everything above the board count as taken
+func (b *Board) Taken(p Point) bool {
+	if !b.inside(p) {
+		return true
+	}
+	return b.Rows[p.Row]&(1<<p.Col) != 0
+}
+
+// checks if a shape is inside the board and only covers empty pixels
+func (b *Board) Fits(s Shape) bool {
+	for _, p := range s {
+		if b.Taken(p) {
+			return false
+		}
+	}
+	return true
+}
+
+// is every pixel of the board empty
+func (b *Board) Empty() bool {
+	for _, row := range b.Rows {
+		if row != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// removes the full rows and moves everything above them down, returns how many rows were removed
+func (b *Board) ClearLines() int {
+	full := b.fullRow()
+	lines := 0
+	for i := 0; i < b.Height; i++ {
+		if b.Rows[i] == full {
+			lines++
+			continue
+		}
+		// moving this row down by how many rows under it were removed
+		if lines > 0 {
+			b.Rows[i-lines] = b.Rows[i]
+			copy(b.Pixels[i-lines], b.Pixels[i])
+		}
+	}
+	// the top rows were moved down, so they are now empty
+	for i := b.Height - lines; i < b.Height; i++ {
+		b.Rows[i] = 0
+		for j := range b.Pixels[i] {
+			b.Pixels[i][j] = Pixel(0)
+		}
+	}
+	return lines
+}
+
+// convert the int tetro to the shape of the standard tetromino where it spawns on the standard board
+func (t Tetro) TetroToNewShape() Shape {
+	return t.NewTetromino().Shape
+}
+
+// get the point the int tetro rotates around when it spawns on the standard board, in half pixels,
+// for every tetro but I and O this is the middle of the pixel in the center of its flat side
+func (t Tetro) TetroToPivot() Point {
+	return t.NewTetromino().Pivot
+}
+
+// makes a new standard tetromino of this type in its spawn position on the standard board
+func (t Tetro) NewTetromino() *Tetromino {
+	return Tetrominoes.NewTetromino(t, WidthOfBoardInPixels, HeightOfBoardInPixels)
+}
+
+// convert the int tetro to the color of the standard tetromino
+func (t Tetro) TetroToColor() color.RGBA {
+	return Tetrominoes.Color(Pixel(t))
+}
@@ -0,0 +1,261 @@
+package engine
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// the board as it was before it was an array, a pixel for every point on the board,
+// kept here to check the array board against and to see how much quicker it is
+type mapBoard map[Point]Pixel
+
+func newMapBoard() mapBoard {
+	b := make(mapBoard, HeightOfBoardInPixels*WidthOfBoardInPixels)
+	for i := 0; i < HeightOfBoardInPixels; i++ {
+		for j := 0; j < WidthOfBoardInPixels; j++ {
+			b[Point{i, j}] = Pixel(0)
+		}
+	}
+	return b
+}
+
+func (b mapBoard) fits(s Shape) bool {
+	for _, p := range s {
+		if p.Row < 0 || p.Row >= HeightOfBoardInPixels || p.Col < 0 || p.Col >= WidthOfBoardInPixels {
+			return false
+		}
+		if b[p] != Pixel(0) {
+			return false
+		}
+	}
+	return true
+}
+
+func (b mapBoard) clearLines() int {
+	lines := 0
+	for i := 0; i < HeightOfBoardInPixels; i++ {
+		line_cleared := true
+		for j := 0; j < WidthOfBoardInPixels; j++ {
+			if b[Point{i, j}] == Pixel(0) {
+				line_cleared = false
+				break
+			}
+		}
+		if line_cleared {
+			lines++
+			continue
+		}
+		if lines > 0 {
+			for j := 0; j < WidthOfBoardInPixels; j++ {
+				b[Point{i - lines, j}] = b[Point{i, j}]
+			}
+		}
+	}
+	for i := HeightOfBoardInPixels - lines; i < HeightOfBoardInPixels; i++ {
+		for j := 0; j < WidthOfBoardInPixels; j++ {
+			b[Point{i, j}] = Pixel(0)
+		}
+	}
+	return lines
+}
+
+// a stack of rows to fill both kinds of board with, every third row is full so there are lines to clear
+// and the rest have a few random holes
+func messyRows(seed int64) [][WidthOfBoardInPixels]Pixel {
+	rng := rand.New(rand.NewSource(seed))
+	rows := make([][WidthOfBoardInPixels]Pixel, 12)
+	for i := range rows {
+		for j := range rows[i] {
+			if i%3 == 0 || rng.Intn(4) != 0 {
+				rows[i][j] = Pixel(rng.Intn(7) + 1)
+			}
+		}
+	}
+	return rows
+}
+
+func fillBoards(seed int64) (Board, mapBoard) {
+	board, old := NewStandardBoard(), newMapBoard()
+	for i, row := range messyRows(seed) {
+		for j, pixel := range row {
+			board.Set(Point{i, j}, pixel)
+			old[Point{i, j}] = pixel
+		}
+	}
+	return board, old
+}
+
+// every shape of every tetro at every place on the board, whether it fits or not
+func everyShape() []Shape {
+	var shapes []Shape
+	for t := Tetro(1); t <= 7; t++ {
+		piece := *t.NewTetromino()
+		for turn := 0; turn < 4; turn++ {
+			rotated, _, _ := piece.Rotated(turn, func(Shape) bool { return true })
+			for rows := -HeightOfBoardInPixels; rows <= 0; rows++ {
+				for cols := -WidthOfBoardInPixels / 2; cols <= WidthOfBoardInPixels/2; cols++ {
+					shape := make(Shape, len(rotated.Shape))
+					for i, p := range rotated.Shape {
+						shape[i] = Point{Row: p.Row + rows, Col: p.Col + cols}
+					}
+					shapes = append(shapes, shape)
+				}
+			}
+		}
+	}
+	return shapes
+}
+
+// finds every place each tetro can land by moving, turning and falling from where it spawns, the way a bot searches,
+// returns how many there are
+func countLandings(fits func(Shape) bool) int {
+	type position struct {
+		tetro    Tetro
+		rotation Rotation
+		pivot    Point
+	}
+	landings := 0
+	for t := Tetro(1); t <= 7; t++ {
+		start := *t.NewTetromino()
+		seen := map[position]bool{{t, start.Rotation, start.Pivot}: true}
+		queue := []Tetromino{start}
+		for len(queue) > 0 {
+			piece := queue[0]
+			queue = queue[1:]
+
+			down := piece.shifted(0)
+			for i := range down.Shape {
+				down.Shape[i].Row--
+			}
+			down.Pivot.Row -= 2
+			if !fits(down.Shape) {
+				landings++
+			}
+
+			next := []Tetromino{piece.shifted(-1), piece.shifted(1), down}
+			for _, turn := range []int{1, -1} {
+				if rotated, _, ok := piece.Rotated(turn, fits); ok {
+					next = append(next, rotated)
+				}
+			}
+			for _, p := range next {
+				pos := position{t, p.Rotation, p.Pivot}
+				if seen[pos] || !fits(p.Shape) {
+					continue
+				}
+				seen[pos] = true
+				queue = append(queue, p)
+			}
+		}
+	}
+	return landings
+}
+
+func TestBoardMatchesMapBoard(t *testing.T) {
+	for seed := int64(1); seed <= 20; seed++ {
+		board, old := fillBoards(seed)
+
+		for _, s := range everyShape() {
+			if board.Fits(s) != old.fits(s) {
+				t.Fatalf("seed %d: Fits(%v) = %v, the map board says %v", seed, s, board.Fits(s), old.fits(s))
+			}
+		}
+		if got, want := countLandings(board.Fits), countLandings(old.fits); got != want {
+			t.Fatalf("seed %d: %d landings, the map board has %d", seed, got, want)
+		}
+
+		if got, want := board.ClearLines(), old.clearLines(); got != want {
+			t.Fatalf("seed %d: cleared %d lines, the map board cleared %d", seed, got, want)
+		}
+		for i := 0; i < HeightOfBoardInPixels; i++ {
+			for j := 0; j < WidthOfBoardInPixels; j++ {
+				p := Point{i, j}
+				if board.Get(p) != old[p] || board.Taken(p) != (old[p] != Pixel(0)) {
+					t.Fatalf("seed %d: after clearing %v is %d, the map board has %d", seed, p, board.Get(p), old[p])
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkCollisionMap(b *testing.B) {
+	_, old := fillBoards(1)
+	shapes := everyShape()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, s := range shapes {
+			old.fits(s)
+		}
+	}
+}
+
+func BenchmarkCollisionArray(b *testing.B) {
+	board, _ := fillBoards(1)
+	shapes := everyShape()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, s := range shapes {
+			board.Fits(s)
+		}
+	}
+}
+
+func BenchmarkMoveGenerationMap(b *testing.B) {
+	_, old := fillBoards(1)
+	for n := 0; n < b.N; n++ {
+		countLandings(old.fits)
+	}
+}
+
+func BenchmarkMoveGenerationArray(b *testing.B) {
+	board, _ := fillBoards(1)
+	for n := 0; n < b.N; n++ {
+		countLandings(board.Fits)
+	}
+}
+
+func BenchmarkClearLinesMap(b *testing.B) {
+	_, filled := fillBoards(1)
+	for n := 0; n < b.N; n++ {
+		b.StopTimer()
+		old := make(mapBoard, len(filled))
+		for p, pixel := range filled {
+			old[p] = pixel
+		}
+		b.StartTimer()
+		old.clearLines()
+	}
+}
+
+func BenchmarkClearLinesArray(b *testing.B) {
+	filled, _ := fillBoards(1)
+	for n := 0; n < b.N; n++ {
+		b.StopTimer()
+		board := filled.Clone()
+		b.StartTimer()
+		board.ClearLines()
+	}
+}
+
+func TestFallingPieceIsNotInTheStack(t *testing.T) {
+	g := NewGame(1, NewBagRandomizer(1, 1))
+	g.CurrentPiece = Tetro(4).NewTetromino()
+
+	// the I spawning across the middle of the top row would fill it if it were part of the stack
+	for j := 0; j < WidthOfBoardInPixels; j++ {
+		if !ContainsShape(g.CurrentPiece.Shape, &Point{HeightOfBoardInPixels - 1, j}) {
+			g.PlayingBoard.Set(Point{HeightOfBoardInPixels - 1, j}, GarbagePixel)
+		}
+	}
+	if g.Collides(g.CurrentPiece.Shape) {
+		t.Fatal("the falling piece collides with its own pixels")
+	}
+	if lines := g.check_lines(); lines != 0 {
+		t.Fatalf("cleared %d lines with only the falling piece filling the row", lines)
+	}
+
+	g.LockPiece()
+	if g.LinesCleared != 1 {
+		t.Fatalf("cleared %d lines once the piece locked, want 1", g.LinesCleared)
+	}
+}
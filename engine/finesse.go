@@ -0,0 +1,141 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+)
+
+// starts counting keys again for a piece that just appeared
+func (g *Game) resetFinesse() {
+	g.PieceKeys = 0
+	g.PieceSoftDropped = false
+}
+
+// counts the keys that move and rotate the current piece, holding a key down only counts once however far DAS takes it
+func (g *Game) countKeys(in, pressed Input) {
+	for _, key := range []bool{pressed.Left, pressed.Right, pressed.RotateClockWise, pressed.RotateCounterClockWise, pressed.Rotate180} {
+		if key {
+			g.PieceKeys++
+		}
+	}
+	if in.SoftDrop {
+		g.PieceSoftDropped = true
+	}
+}
+
+// compares the keys pressed for the piece that is locking with the fewest that could have put it there,
+// pieces that were soft dropped or spun into place aren't judged since the fewest keys on an empty board don't apply to them
+func (g *Game) judgeFinesse(tspin TSpin) {
+	g.LastFinesseFaults = 0
+	if g.PieceSoftDropped || tspin != TSpinNone {
+		return
+	}
+	best := g.finesseKeys(g.CurrentPiece.Tetro, g.CurrentPiece.Shape, g.Finesse180)
+	if best < 0 {
+		return
+	}
+
+	faults := g.PieceKeys - best
+	if faults < 0 {
+		faults = 0
+	}
+	g.FinessePieces++
+	if faults == 0 {
+		g.FinessePerfect++
+	}
+	g.FinesseFaults += faults
+	g.LastFinesseFaults = faults
+}
+
+// the percentage of judged pieces that were placed with the fewest keys, 100 before any have been judged
+func (g *Game) FinessePercent() float64 {
+	if g.FinessePieces == 0 {
+		return 100
+	}
+	return 100 * float64(g.FinessePerfect) / float64(g.FinessePieces)
+}
+
+// the pixels of a shape moved down to the floor, sorted, so shapes in the same columns compare equal
+// whatever height they locked at
+func floorShape(s Shape) string {
+	lowest := lowestRow(s)
+	moved := make(Shape, len(s))
+	for i, p := range s {
+		moved[i] = Point{Row: p.Row - lowest, Col: p.Col}
+	}
+	sort.Slice(moved, func(i, j int) bool {
+		if moved[i].Row != moved[j].Row {
+			return moved[i].Row < moved[j].Row
+		}
+		return moved[i].Col < moved[j].Col
+	})
+	return fmt.Sprint(moved)
+}
+
+// returns the tetromino moved by cols
+func (t Tetromino) shifted(cols int) Tetromino {
+	shape := make(Shape, len(t.Shape))
+	for i, p := range t.Shape {
+		shape[i] = Point{Row: p.Row, Col: p.Col + cols}
+	}
+	t.Shape = shape
+	t.Pivot.Col += 2 * cols
+	return t
+}
+
+// the fewest keys that get a tetro from where it spawns to above where target is, on an empty board,
+// each tap left or right, each hold to the wall with DAS and each rotation is one key,
+// returns -1 if target can't be reached
+func (g *Game) finesseKeys(t Tetro, target Shape, use180 bool) int {
+	empty := NewBoard(g.PlayingBoard.Width, g.PlayingBoard.Visible)
+	inside := empty.Fits
+	turns := []int{1, -1}
+	if use180 {
+		turns = append(turns, 2)
+	}
+
+	type position struct {
+		rotation Rotation
+		pivot    Point
+	}
+	want := floorShape(target)
+	start := *g.newPiece(t)
+	keys := map[position]int{{start.Rotation, start.Pivot}: 0}
+	queue := []Tetromino{start}
+	for len(queue) > 0 {
+		piece := queue[0]
+		queue = queue[1:]
+		n := keys[position{piece.Rotation, piece.Pivot}]
+		if floorShape(piece.Shape) == want {
+			return n
+		}
+
+		var next []Tetromino
+		for _, dir := range []int{-1, 1} {
+			// a tap moves one pixel, DAS moves all the way to the wall
+			if tapped := piece.shifted(dir); inside(tapped.Shape) {
+				next = append(next, tapped)
+				wall := tapped
+				for inside(wall.shifted(dir).Shape) {
+					wall = wall.shifted(dir)
+				}
+				next = append(next, wall)
+			}
+		}
+		for _, turn := range turns {
+			if rotated, _, ok := piece.Rotated(turn, inside); ok {
+				next = append(next, rotated)
+			}
+		}
+
+		for _, p := range next {
+			pos := position{p.Rotation, p.Pivot}
+			if _, seen := keys[pos]; seen {
+				continue
+			}
+			keys[pos] = n + 1
+			queue = append(queue, p)
+		}
+	}
+	return -1
+}
@@ -0,0 +1,152 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+// where a piece ends up after the keys on the board, L and R move it, C, A and F turn it
+// clockwise, anticlockwise and round, W and E slide it to the left and right walls and D drops it to the floor
+func pieceAfter(g *Game, tetro Tetro, keys string) Tetromino {
+	piece := *g.newPiece(tetro)
+	slide := func(cols int) {
+		for g.PlayingBoard.Fits(piece.shifted(cols).Shape) {
+			piece = piece.shifted(cols)
+		}
+	}
+	for _, key := range keys {
+		switch key {
+		case 'L':
+			piece = piece.shifted(-1)
+		case 'R':
+			piece = piece.shifted(1)
+		case 'W':
+			slide(-1)
+		case 'E':
+			slide(1)
+		case 'D':
+			for {
+				down := make(Shape, len(piece.Shape))
+				for i, p := range piece.Shape {
+					down[i] = Point{Row: p.Row - 1, Col: p.Col}
+				}
+				if !g.PlayingBoard.Fits(down) {
+					break
+				}
+				piece.Shape = down
+				piece.Pivot.Row -= 2
+			}
+		case 'C', 'A', 'F':
+			turns := map[rune]int{'C': 1, 'A': -1, 'F': 2}[key]
+			piece, _, _ = piece.Rotated(turns, g.PlayingBoard.Fits)
+		}
+	}
+	return piece
+}
+
+func TestFinesseKeys(t *testing.T) {
+	var (
+		O = Tetro(1)
+		I = Tetro(4)
+		T = Tetro(5)
+	)
+	tests := []struct {
+		name   string
+		tetro  Tetro
+		keys   string
+		use180 bool
+		want   int
+	}{
+		{"T where it spawns", T, "", false, 0},
+		{"T one left", T, "L", false, 1},
+		{"T flat at column 0", T, "W", false, 1},
+		{"T flat two right", T, "RR", false, 2},
+		{"T pointing down at column 0", T, "FW", false, 3},
+		{"T pointing down at column 0 with 180", T, "FW", true, 2},
+		{"T pointing down", T, "F", false, 2},
+		{"T pointing down with 180", T, "F", true, 1},
+		{"I standing against the left wall", I, "CW", false, 2},
+		{"O at column 0", O, "W", false, 1},
+		{"O one right", O, "R", false, 1},
+	}
+	for _, test := range tests {
+		g := NewGame(1, NewBagRandomizer(1, 1))
+		target := pieceAfter(&g, test.tetro, test.keys).Shape
+		if got := g.finesseKeys(test.tetro, target, test.use180); got != test.want {
+			t.Errorf("%s: %d keys, want %d", test.name, got, test.want)
+		}
+	}
+}
+
+// plays the keys on the falling piece a step at a time, letting go between each, then hard drops it,
+// L and R tap left and right, C, A and F turn the piece and W holds left long enough for DAS to reach the wall
+func playKeys(g *Game, keys string) {
+	const dt = 16 * time.Millisecond
+	for _, key := range keys {
+		var in Input
+		steps := 1
+		switch key {
+		case 'L':
+			in.Left = true
+		case 'R':
+			in.Right = true
+		case 'C':
+			in.RotateClockWise = true
+		case 'A':
+			in.RotateCounterClockWise = true
+		case 'F':
+			in.Rotate180 = true
+		case 'W':
+			in.Left = true
+			steps = 40
+		}
+		for i := 0; i < steps; i++ {
+			g.Step(dt, in)
+		}
+		g.Step(dt, Input{})
+	}
+	g.Step(dt, Input{HardDrop: true})
+}
+
+func TestFinesseFaults(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    string
+		use180  bool
+		faults  int
+		perfect int
+	}{
+		{"tapping once to one left", "L", false, 0, 1},
+		{"going to the wall with DAS", "W", false, 0, 1},
+		{"going to the wall and tapping back twice for two left is an extra tap", "WRR", false, 1, 0},
+		{"tapping left and back again", "LR", false, 2, 0},
+		{"turning twice without 180", "CC", false, 0, 1},
+		{"turning twice when 180 would have done", "CC", true, 1, 0},
+		{"turning round with 180", "F", true, 0, 1},
+	}
+	for _, test := range tests {
+		g := NewGame(1, NewBagRandomizer(1, 1))
+		g.Finesse180 = test.use180
+		g.SetNextTetroFromBag()
+		g.CurrentPiece = g.newPiece(Tetro(5))
+		playKeys(&g, test.keys)
+		if g.PiecesPlaced != 1 {
+			t.Fatalf("%s: %d pieces placed, want 1", test.name, g.PiecesPlaced)
+		}
+		if g.FinesseFaults != test.faults || g.LastFinesseFaults != test.faults || g.FinessePieces != 1 || g.FinessePerfect != test.perfect {
+			t.Errorf("%s: %d faults (%d last) over %d pieces with %d perfect, want %d faults over 1 piece with %d perfect",
+				test.name, g.FinesseFaults, g.LastFinesseFaults, g.FinessePieces, g.FinessePerfect, test.faults, test.perfect)
+		}
+	}
+}
+
+func TestFinesseSoftDropNotJudged(t *testing.T) {
+	g := NewGame(1, NewBagRandomizer(1, 1))
+	g.SetNextTetroFromBag()
+	g.Step(16*time.Millisecond, Input{Left: true})
+	g.Step(16*time.Millisecond, Input{Right: true, SoftDrop: true})
+	g.Step(16*time.Millisecond, Input{HardDrop: true})
+	if g.FinessePieces != 0 || g.FinesseFaults != 0 || g.FinessePercent() != 100 {
+		t.Fatalf("a soft dropped piece was judged, %d faults over %d pieces", g.FinesseFaults, g.FinessePieces)
+	}
+}
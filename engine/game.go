@@ -0,0 +1,399 @@
+// Package engine holds the rules of the game with no knowledge of how it is drawn,
+// a frontend drives it by calling Game.Step with the time passed and the keys held
+package engine
+
+import (
+	"time"
+)
+
+// this function checks if a point is in a shape
+func ContainsShape(sl Shape, point *Point) bool {
+	for _, v := range sl {
+		if v == *point {
+			return true
+		}
+	}
+	return false
+}
+
+// the main game struct for the game loop, and controlling score and whatnot, everything to do with the game is in this struct
+type Game struct {
+	// the pixels of the stack of locked pieces, the falling piece isn't part of it until it locks
+	PlayingBoard Board
+
+	// the kinds of piece the game deals, the standard tetrominoes unless it was made with another set
+	Pieces *PieceSet
+
+	// the current tetro thats falling
+	CurrentPiece *Tetromino
+
+	// the held piece, this is an int because we convert it to a tetro when it starts falling
+	HeldPiece int
+
+	// can we hold a piece, aka, have we already held a piece since a piece has fallen
+	CanHold bool
+
+	// the tetros that are coming next, in order, this is refilled from the randomizer
+	// whenever a tetro is taken so it always holds QueueLength tetros
+	NextQueue []Tetro
+
+	// how many tetros can be seen coming next
+	QueueLength int
+
+	// the seed the randomizer was made with, the same seed and randomizer give the same order of tetros
+	Seed int64
+
+	// decides which tetros go into the bag
+	Randomizer Randomizer
+
+	// the current score of the game
+	Score int
+
+	// the total amount of lines that have been cleared
+	LinesCleared int
+
+	// the current level of the game
+	Level int
+
+	// the level the game started at
+	StartLevel int
+
+	// how many lines it takes to go up a level
+	LevelGoal LevelGoal
+
+	// how many lines have been cleared since the last level up, counting towards the level goal
+	GoalLines int
+
+	// how long the game has been played for, not counting time spent paused
+	PlayTime time.Duration
+
+	// how many pieces in a row have cleared lines, this is -1 when the last piece didn't clear any
+	Combo int
+
+	// was the last line clear a difficult one (a tetris or a t-spin), the next difficult clear gets a back-to-back bonus
+	BackToBack bool
+
+	// was the last thing that moved the current piece a rotation, a t-spin has to end with a rotation
+	LastMoveRotation bool
+
+	// which of the wall kicks was used by the last rotation, 0 being no kick
+	LastKick int
+
+	// was the last rotation a half turn, whose last kick doesn't make a full t-spin
+	LastHalfTurn bool
+
+	// functions that are called every time a piece locks, with what it scored
+	clearListeners []func(ClearEvent)
+
+	// every step of the game since StartRecording was called, nil if the game isn't being recorded
+	Recording *Replay
+
+	// how long the game has been running since recording started
+	recordTime time.Duration
+
+	// is the game over, have we broken one of the top out rules
+	GameOver bool
+
+	// how the game was lost, empty until it is
+	GameOverReason GameOverReason
+
+	// which ways of topping out end the game
+	TopOut TopOutRules
+
+	// is the game paused
+	Paused bool
+
+	// the rules the game is being played to
+	Mode Mode
+
+	// has the goal of the mode been reached, this ends the game without it being lost
+	Finished bool
+
+	// how many pieces have been locked in place
+	PiecesPlaced int
+
+	// how many keys have been pressed to move and rotate the current piece, its finesse is judged on this when it locks
+	PieceKeys int
+
+	// has soft drop been held while the current piece was falling, pieces that were soft dropped aren't judged on finesse
+	PieceSoftDropped bool
+
+	// how many more keys were pressed than were needed, over every piece judged on finesse
+	FinesseFaults int
+
+	// how many more keys were pressed than were needed for the last piece that locked
+	LastFinesseFaults int
+
+	// how many pieces have been judged on finesse, and how many of those were placed with as few keys as they could be
+	FinessePieces  int
+	FinessePerfect int
+
+	// can the 180 rotation be used when working out the fewest keys for a piece, set when the player has a key for it
+	Finesse180 bool
+
+	// garbage sent by an opponent that hasn't risen into the board yet, oldest first
+	IncomingGarbage []Garbage
+
+	// how fast the piece falls at each level
+	Gravity GravityCurve
+
+	// how many milliseconds the piece can rest on the stack before it is locked in place
+	LockDelayMillis int
+
+	// what resets the lock delay
+	LockMode LockMode
+
+	// how many times moving or rotating can reset the lock delay in extended mode
+	MaxLockResets int
+
+	// how many milliseconds to wait after clearing lines before the next piece appears
+	LineClearDelayMillis int
+
+	// how the piece responds to keys being held
+	Handling Handling
+
+	// how far the piece has fallen towards the next pixel down, in rows,
+	// gravity adds to this every step and the piece falls a pixel each time it reaches 1
+	FallProgress float64
+
+	// how long the piece has been resting on the stack since the lock delay was last reset
+	LockTimer time.Duration
+
+	// how many times the current piece has been moved or rotated since it last fell to a new lowest row
+	LockResets int
+
+	// the lowest row the current piece has reached
+	LowestRow int
+
+	// which way the piece is being shifted, -1 for left, 1 for right and 0 for neither
+	ShiftDirection int
+
+	// how long the current shift direction has been held, once this reaches the DAS the piece moves on its own
+	DASTimer time.Duration
+
+	// how long it has been since the piece last moved on its own after DAS charged
+	ARRTimer time.Duration
+
+	// how much longer until the next piece appears, while this is counting CurrentPiece is nil
+	SpawnTimer time.Duration
+
+	// the keys that were held on the previous step, used to tell when a key was just pressed
+	LastInput Input
+}
+
+// returns a new game with defaults, taking its tetros from randomizer which was made with seed
+func NewGame(seed int64, randomizer Randomizer) Game {
+	return Game{
+		PlayingBoard:    NewStandardBoard(),
+		Pieces:          Tetrominoes,
+		CurrentPiece:    nil,
+		HeldPiece:       0,
+		CanHold:         true,
+		NextQueue:       nil,
+		QueueLength:     5,
+		Seed:            seed,
+		Randomizer:      randomizer,
+		Score:           0,
+		LinesCleared:    0,
+		Level:           1,
+		StartLevel:      1,
+		LevelGoal:       GoalFixed,
+		Combo:           -1,
+		GameOver:        false,
+		Paused:          false,
+		Mode:            Marathon{},
+		Gravity:         GravityGuideline,
+		LockDelayMillis: 500,
+		LockMode:        LockExtended,
+		MaxLockResets:   15,
+		Handling:        DefaultHandling(),
+		TopOut:          DefaultTopOutRules(),
+	}
+}
+
+// tops up the next queue from the randomizer so it holds QueueLength tetros,
+// the randomizer works out bag boundaries itself so the queue can see into the next bag
+func (g *Game) fillQueue() {
+	for len(g.NextQueue) < g.QueueLength || len(g.NextQueue) == 0 {
+		g.NextQueue = append(g.NextQueue, g.Randomizer.Next())
+	}
+}
+
+// gets the next n tetros that are coming without taking them out of the queue,
+// n can't be more than QueueLength
+func (g *Game) PeekNext(n int) []Tetro {
+	g.fillQueue()
+	if n > len(g.NextQueue) {
+		n = len(g.NextQueue)
+	}
+	return g.NextQueue[:n]
+}
+
+// takes the next tetro from the queue and sets it as the current tetro,
+// the queue is refilled straight away so there is always a next piece to show
+func (g *Game) SetNextTetroFromBag() {
+	g.fillQueue()
+	g.CurrentPiece = g.newPiece(g.NextQueue[0])
+	g.NextQueue = g.NextQueue[1:]
+	g.fillQueue()
+	g.LastMoveRotation = false
+	g.resetLock()
+	g.resetFinesse()
+	g.checkSpawn()
+}
+
+// makes a new piece of the game's piece set where it spawns on the game's board
+func (g *Game) newPiece(t Tetro) *Tetromino {
+	return g.Pieces.NewTetromino(t, g.PlayingBoard.Width, g.PlayingBoard.Height)
+}
+
+// changes the size of the board to width pixels wide with visible rows that can be seen, which empties it,
+// this has to be done before the first piece spawns
+func (g *Game) SetBoardSize(width, visible int) {
+	g.PlayingBoard = NewBoard(width, visible)
+}
+
+// checks if a shape would go outside the board or overlap the locked stack
+func (g *Game) Collides(s Shape) bool {
+	return !g.PlayingBoard.Fits(s)
+}
+
+// returns the shape moved by rows and cols
+func moved(s Shape, rows, cols int) Shape {
+	out := make(Shape, len(s))
+	for i, p := range s {
+		out[i] = Point{Row: p.Row + rows, Col: p.Col + cols}
+	}
+	return out
+}
+
+// checks if something is under the current tetro, the floor counts as something
+func (g *Game) CheckIfSomethingUnder(s *Shape) bool {
+	if s == nil {
+		s = &g.CurrentPiece.Shape
+	}
+	return g.Collides(moved(*s, -1, 0))
+}
+
+// checks if something is to the right of the current tetro, the wall counts as something
+func (g *Game) CheckIfSomethingRight() bool {
+	return g.Collides(moved(g.CurrentPiece.Shape, 0, 1))
+}
+
+// checks if something is to the left of the current tetro, the wall counts as something
+func (g *Game) CheckIfSomethingLeft() bool {
+	return g.Collides(moved(g.CurrentPiece.Shape, 0, -1))
+}
+
+// moves the current tetro down
+func (g *Game) GravityDrop() bool {
+	if g.CheckIfSomethingUnder(nil) {
+		return false
+	}
+
+	for j := 0; j < len(g.CurrentPiece.Shape); j++ {
+		g.CurrentPiece.Shape[j].Row -= 1
+	}
+	g.CurrentPiece.Pivot.Row -= 2
+	g.LastMoveRotation = false
+	g.pieceFell()
+	return true
+}
+
+// moves the current tetro right
+func (g *Game) MoveRight() bool {
+	if g.CheckIfSomethingRight() {
+		return false
+	}
+
+	for j := 0; j < len(g.CurrentPiece.Shape); j++ {
+		g.CurrentPiece.Shape[j].Col += 1
+	}
+	g.CurrentPiece.Pivot.Col += 2
+	g.LastMoveRotation = false
+	g.pieceMoved()
+	return true
+}
+
+// moves the current tetro to the left
+func (g *Game) MoveLeft() bool {
+	if g.CheckIfSomethingLeft() {
+		return false
+	}
+
+	for j := 0; j < len(g.CurrentPiece.Shape); j++ {
+		g.CurrentPiece.Shape[j].Col -= 1
+	}
+	g.CurrentPiece.Pivot.Col -= 2
+	g.LastMoveRotation = false
+	g.pieceMoved()
+	return true
+}
+
+// check for lines that should be cleared, clear them and move everything above them down,
+// returns how many lines were cleared
+func (game *Game) check_lines() int {
+	lines := game.PlayingBoard.ClearLines()
+	game.LinesCleared += lines
+	game.addGoalLines(lines)
+
+	return lines
+}
+
+// swap the current piece with the held piece, this can only be done once per piece
+func (g *Game) HoldTetro() {
+	if !g.CanHold {
+		return
+	}
+	if g.HeldPiece != 0 {
+		temp := g.HeldPiece
+		g.HeldPiece = int(g.CurrentPiece.Tetro)
+		g.CurrentPiece = g.newPiece(Tetro(temp))
+		g.LastMoveRotation = false
+		g.resetLock()
+		g.resetFinesse()
+		g.checkSpawn()
+	} else {
+		g.HeldPiece = int(g.CurrentPiece.Tetro)
+
+		g.SetNextTetroFromBag()
+	}
+	g.CanHold = false
+}
+
+// gets the coordinates of where the current tetro would land if it was hard dropped,
+// this is nil while there's no current tetro
+func (g *Game) GhostShape() Shape {
+	if g.CurrentPiece == nil {
+		return nil
+	}
+	shape := make(Shape, len(g.CurrentPiece.Shape))
+	copy(shape, g.CurrentPiece.Shape)
+	for !g.CheckIfSomethingUnder(&shape) {
+		for j := 0; j < len(shape); j++ {
+			shape[j].Row -= 1
+		}
+	}
+	return shape
+}
+
+// the pixel the ghost piece is drawn with, it's never part of the stack
+const GhostPixel = Pixel(-2)
+
+// the board as it should be shown, the locked stack with the falling piece on top of it,
+// and with the ghost piece where it would land when ghost is set
+func (g *Game) ComposeBoard(ghost bool) Board {
+	board := g.PlayingBoard.Clone()
+	if g.CurrentPiece == nil {
+		return board
+	}
+	if ghost {
+		for _, p := range g.GhostShape() {
+			board.Set(p, GhostPixel)
+		}
+	}
+	for _, p := range g.CurrentPiece.Shape {
+		board.Set(p, Pixel(g.CurrentPiece.Tetro))
+	}
+	return board
+}
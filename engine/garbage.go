@@ -0,0 +1,145 @@
+package engine
+
+import "math/rand"
+
+// the pixel garbage rows are made of, they are grey so they stand out from the tetros,
+// it's below zero so it can't be mistaken for a piece of any piece set
+const GarbagePixel = Pixel(-1)
+
+// Garbage is a batch of garbage rows sent by an opponent, all with their hole in the same column
+type Garbage struct {
+	Lines      int
+	HoleColumn int
+}
+
+// pushes the stack up by lines rows and fills the rows underneath with garbage,
+// every garbage row is full apart from a hole at holeColumn,
+// if the current piece ends up inside the stack it is pushed up until it fits,
+// anything pushed off the top of the board tops the game out
+func (g *Game) AddGarbage(lines int, holeColumn int) {
+	if lines <= 0 {
+		return
+	}
+
+	board := &g.PlayingBoard
+	// garbage sent from a wider board still needs a hole in this one, and so does a hole left of the board
+	holeColumn = (holeColumn%board.Width + board.Width) % board.Width
+	for i := board.Height - 1; i >= 0; i-- {
+		if i+lines >= board.Height {
+			if board.Rows[i] != 0 {
+				g.topOut(GarbageOut)
+			}
+			continue
+		}
+		board.Rows[i+lines] = board.Rows[i]
+		copy(board.Pixels[i+lines], board.Pixels[i])
+	}
+	for i := 0; i < lines && i < board.Height; i++ {
+		for j := 0; j < board.Width; j++ {
+			if j == holeColumn {
+				board.Set(Point{i, j}, Pixel(0))
+			} else {
+				board.Set(Point{i, j}, GarbagePixel)
+			}
+		}
+	}
+
+	if g.CurrentPiece == nil {
+		return
+	}
+	for g.overlapsStack(g.CurrentPiece.Shape) {
+		for i := range g.CurrentPiece.Shape {
+			g.CurrentPiece.Shape[i].Row++
+		}
+		g.CurrentPiece.Pivot.Row += 2
+	}
+	for _, p := range g.CurrentPiece.Shape {
+		if p.Row >= g.PlayingBoard.Height {
+			g.topOut(GarbageOut)
+		}
+	}
+	// the piece is now lower than before compared to the stack, so falling from here counts as falling further
+	g.LowestRow = lowestRow(g.CurrentPiece.Shape)
+}
+
+// adds lines rows of garbage one at a time, each with its hole in a random column different from the one under it,
+// the board has to be at least MinBoardWidth wide
+func (g *Game) AddMessyGarbage(lines int, rng *rand.Rand) {
+	hole := -1
+	for i := 0; i < lines; i++ {
+		if hole < 0 {
+			hole = rng.Intn(g.PlayingBoard.Width)
+		} else {
+			// picking from every column but the one under it
+			next := rng.Intn(g.PlayingBoard.Width - 1)
+			if next >= hole {
+				next++
+			}
+			hole = next
+		}
+		g.AddGarbage(1, hole)
+	}
+}
+
+// is any pixel of the shape on something already in the stack, pixels above the board don't overlap anything
+func (g *Game) overlapsStack(s Shape) bool {
+	for _, p := range s {
+		if g.PlayingBoard.Get(p) != Pixel(0) {
+			return true
+		}
+	}
+	return false
+}
+
+// how many rows on the board still have garbage in them
+func (g *Game) GarbageLeft() int {
+	rows := 0
+	for _, row := range g.PlayingBoard.Pixels {
+		for _, pixel := range row {
+			if pixel == GarbagePixel {
+				rows++
+				break
+			}
+		}
+	}
+	return rows
+}
+
+// queues garbage sent by an opponent, it rises into the board the next time a piece locks without clearing anything
+func (g *Game) ReceiveGarbage(lines int, holeColumn int) {
+	if lines > 0 {
+		g.IncomingGarbage = append(g.IncomingGarbage, Garbage{Lines: lines, HoleColumn: holeColumn})
+	}
+}
+
+// how many rows of garbage are waiting to rise into the board, for the incoming garbage meter
+func (g *Game) IncomingLines() int {
+	lines := 0
+	for _, garbage := range g.IncomingGarbage {
+		lines += garbage.Lines
+	}
+	return lines
+}
+
+// uses lines of attack to cancel out incoming garbage, oldest first,
+// returns how many lines of the attack are left over to send to the opponent
+func (g *Game) CancelGarbage(lines int) int {
+	for lines > 0 && len(g.IncomingGarbage) > 0 {
+		if g.IncomingGarbage[0].Lines > lines {
+			g.IncomingGarbage[0].Lines -= lines
+			return 0
+		}
+		lines -= g.IncomingGarbage[0].Lines
+		g.IncomingGarbage = g.IncomingGarbage[1:]
+	}
+	return lines
+}
+
+// pushes all the incoming garbage into the board under the stack, the piece that just locked being part of the stack
+func (g *Game) riseGarbage() {
+	g.CurrentPiece = nil
+	for _, garbage := range g.IncomingGarbage {
+		g.AddGarbage(garbage.Lines, garbage.HoleColumn)
+	}
+	g.IncomingGarbage = nil
+}
@@ -0,0 +1,145 @@
+package engine
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// the column of the hole in a garbage row, -1 if the row is full
+func holeIn(b *Board, row int) int {
+	for j := 0; j < b.Width; j++ {
+		if !b.Taken(Point{row, j}) {
+			return j
+		}
+	}
+	return -1
+}
+
+func TestGarbageHoleWraps(t *testing.T) {
+	g := NewGame(1, NewBagRandomizer(1, 1))
+	g.SetBoardSize(6, 20)
+	for _, hole := range []struct{ sent, want int }{{2, 2}, {8, 2}, {-1, 5}, {-8, 4}} {
+		g.AddGarbage(1, hole.sent)
+		if got := holeIn(&g.PlayingBoard, 0); got != hole.want {
+			t.Fatalf("garbage sent with its hole in column %d has it in column %d, want %d", hole.sent, got, hole.want)
+		}
+	}
+}
+
+func TestMessyGarbageHoles(t *testing.T) {
+	// every column gets a hole in the first row for some seed, and no row has its hole over the one under it
+	first := map[int]bool{}
+	for seed := int64(0); seed < 200; seed++ {
+		g := NewGame(seed, NewBagRandomizer(seed, 1))
+		g.SetBoardSize(MinBoardWidth+2, 20)
+		g.AddMessyGarbage(10, rand.New(rand.NewSource(seed)))
+		first[holeIn(&g.PlayingBoard, 9)] = true
+		for i := 0; i < 9; i++ {
+			if holeIn(&g.PlayingBoard, i) == holeIn(&g.PlayingBoard, i+1) {
+				t.Fatalf("seed %d: rows %d and %d both have their hole in column %d", seed, i, i+1, holeIn(&g.PlayingBoard, i))
+			}
+		}
+	}
+	for j := 0; j < MinBoardWidth+2; j++ {
+		if !first[j] {
+			t.Fatalf("the first row never has its hole in column %d", j)
+		}
+	}
+}
+
+func TestGarbagePushesStackUp(t *testing.T) {
+	g := newBoardGame([]string{"XX........", "X........."})
+	g.AddGarbage(3, 4)
+	for i := 0; i < 3; i++ {
+		if hole := holeIn(&g.PlayingBoard, i); hole != 4 {
+			t.Fatalf("garbage row %d has its hole in column %d, want 4", i, hole)
+		}
+	}
+	for _, p := range []Point{{3, 0}, {3, 1}, {4, 0}} {
+		if !g.PlayingBoard.Taken(p) {
+			t.Fatalf("the stack wasn't pushed up to %v", p)
+		}
+	}
+	if g.PlayingBoard.Taken(Point{4, 1}) || g.PlayingBoard.Taken(Point{5, 0}) {
+		t.Fatal("the stack was pushed up too far")
+	}
+	if g.GameOver {
+		t.Fatalf("the game ended with %q", g.GameOverReason)
+	}
+}
+
+func TestGarbagePushesPieceUp(t *testing.T) {
+	g := newBoardGame(nil)
+	for g.GravityDrop() {
+	}
+	before := append(Shape(nil), g.CurrentPiece.Shape...)
+	pivot := g.CurrentPiece.Pivot
+	g.AddGarbage(2, 0)
+	for i, p := range g.CurrentPiece.Shape {
+		if p != (Point{before[i].Row + 2, before[i].Col}) {
+			t.Fatalf("the piece on the floor at %v was pushed up to %v, want 2 rows up", before, g.CurrentPiece.Shape)
+		}
+	}
+	if g.CurrentPiece.Pivot != (Point{pivot.Row + 4, pivot.Col}) {
+		t.Fatalf("the pivot moved from %v to %v", pivot, g.CurrentPiece.Pivot)
+	}
+	if g.Collides(g.CurrentPiece.Shape) || !g.CheckIfSomethingUnder(nil) {
+		t.Fatal("the piece isn't resting on the garbage")
+	}
+}
+
+func TestGarbageOut(t *testing.T) {
+	// the stack going off the top
+	g := newBoardGame(nil)
+	g.PlayingBoard.Set(Point{g.PlayingBoard.Height - 1, 0}, GarbagePixel)
+	g.AddGarbage(1, 5)
+	if !g.GameOver || g.GameOverReason != GarbageOut {
+		t.Fatalf("pushing the stack off the top ended the game with %q, game over %v", g.GameOverReason, g.GameOver)
+	}
+
+	// the piece going off the top, with the stack right up under it
+	g = newBoardGame(nil)
+	top := pieceBottom(&g)
+	for i := 0; i < top; i++ {
+		for j := 0; j < g.PlayingBoard.Width-1; j++ {
+			g.PlayingBoard.Set(Point{i, j}, GarbagePixel)
+		}
+	}
+	g.AddGarbage(2, 9)
+	if !g.GameOver || g.GameOverReason != GarbageOut {
+		t.Fatalf("pushing the piece off the top ended the game with %q, game over %v", g.GameOverReason, g.GameOver)
+	}
+}
+
+func TestCancelGarbage(t *testing.T) {
+	g := newBoardGame(nil)
+	g.ReceiveGarbage(3, 0)
+	g.ReceiveGarbage(2, 1)
+	g.ReceiveGarbage(0, 2)
+	if lines := g.IncomingLines(); lines != 5 {
+		t.Fatalf("%d lines are incoming, want 5", lines)
+	}
+	if sent := g.CancelGarbage(4); sent != 0 || g.IncomingLines() != 1 || g.IncomingGarbage[0].HoleColumn != 1 {
+		t.Fatalf("cancelling 4 lines sent %d and left %v incoming, want 0 sent and 1 line with its hole in column 1", sent, g.IncomingGarbage)
+	}
+	if sent := g.CancelGarbage(3); sent != 2 || len(g.IncomingGarbage) != 0 {
+		t.Fatalf("cancelling 3 lines sent %d and left %v incoming, want 2 sent and nothing incoming", sent, g.IncomingGarbage)
+	}
+}
+
+// incoming garbage rises when a piece locks without clearing anything, and waits when it clears lines
+func TestIncomingGarbageRises(t *testing.T) {
+	g := newBoardGame([]string{"XXXXXXXX.."})
+	g.ReceiveGarbage(2, 3)
+	place(t, &g, placement{Tetro(1), "RRRRD"})
+	if g.IncomingLines() != 2 || g.GarbageLeft() != 0 {
+		t.Fatalf("after a clear %d lines are incoming and %d rows have garbage, want 2 and 0", g.IncomingLines(), g.GarbageLeft())
+	}
+	place(t, &g, placement{Tetro(1), "LLLLD"})
+	if g.IncomingLines() != 0 || g.GarbageLeft() != 2 {
+		t.Fatalf("after locking without a clear %d lines are incoming and %d rows have garbage, want 0 and 2", g.IncomingLines(), g.GarbageLeft())
+	}
+	if hole := holeIn(&g.PlayingBoard, 0); hole != 3 {
+		t.Fatalf("the garbage that rose has its hole in column %d, want 3", hole)
+	}
+}
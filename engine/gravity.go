@@ -0,0 +1,149 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// how long one frame is, gravity is measured in how many rows the piece falls each frame
+const FrameDuration = time.Second / 60
+
+// the fastest gravity there is, 20G, where the piece falls the whole height of the board every frame
+const MaxGravity = 20
+
+// GravityCurve decides how fast the piece falls at each level
+type GravityCurve string
+
+const (
+	// the formula from the Tetris Guideline, (0.8 - (level-1)*0.007)^(level-1) seconds per row,
+	// reaching 20G at level 20
+	GravityGuideline GravityCurve = "guideline"
+
+	// the frames per row the NES version used, level 1 here being level 0 on the NES,
+	// it tops out at a row every frame from level 30
+	GravityNES GravityCurve = "nes"
+
+	// every piece falls straight to the stack as soon as it appears, like the end of TGM
+	Gravity20G GravityCurve = "20g"
+)
+
+// the gravity curves that can be used, for listing in flags and config errors
+var GravityCurves = []GravityCurve{GravityGuideline, GravityNES, Gravity20G}
+
+// how many frames it takes a piece to fall a row on the NES, from level 0 up,
+// every level past the end of the table is one frame per row
+var nesFramesPerRow = []int{
+	48, 43, 38, 33, 28, 23, 18, 13, 8, 6,
+	5, 5, 5, 4, 4, 4, 3, 3, 3,
+	2, 2, 2, 2, 2, 2, 2, 2, 2, 2,
+}
+
+// how many rows the piece falls each frame at level
+func (c GravityCurve) CellsPerFrame(level int) float64 {
+	if level < 1 {
+		level = 1
+	}
+	var cells float64
+	switch c {
+	case GravityNES:
+		frames := 1
+		if level-1 < len(nesFramesPerRow) {
+			frames = nesFramesPerRow[level-1]
+		}
+		cells = 1 / float64(frames)
+	case Gravity20G:
+		cells = MaxGravity
+	default:
+		// the formula only makes sense up to level 20, it starts slowing down again after that
+		if level > 20 {
+			level = 20
+		}
+		seconds := math.Pow(0.8-float64(level-1)*0.007, float64(level-1))
+		cells = 1 / (seconds * 60)
+	}
+	return math.Min(cells, MaxGravity)
+}
+
+// LevelGoal decides how many lines it takes to go up a level
+type LevelGoal string
+
+const (
+	// every level takes 10 lines
+	GoalFixed LevelGoal = "fixed"
+
+	// every level takes 5 lines times the level, so level 1 takes 5 lines, level 2 takes 10 and so on
+	GoalVariable LevelGoal = "variable"
+)
+
+// the level goals that can be used, for listing in flags and config errors
+var LevelGoals = []LevelGoal{GoalFixed, GoalVariable}
+
+// how many lines it takes to get from level to the next one
+func (goal LevelGoal) LinesForLevel(level int) int {
+	if goal == GoalVariable {
+		return 5 * level
+	}
+	return 10
+}
+
+// checks that the gravity curve is one the game knows
+func (c GravityCurve) Valid() error {
+	for _, curve := range GravityCurves {
+		if curve == c {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown gravity curve %q, expected one of %v", c, GravityCurves)
+}
+
+// checks that the level goal is one the game knows
+func (goal LevelGoal) Valid() error {
+	for _, g := range LevelGoals {
+		if g == goal {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown level goal %q, expected one of %v", goal, LevelGoals)
+}
+
+// starts the game at level instead of level 1, this should be called before the first piece spawns
+func (g *Game) SetStartLevel(level int) {
+	if level < 1 {
+		level = 1
+	}
+	g.StartLevel = level
+	g.Level = level
+	g.GoalLines = 0
+}
+
+// how many more lines have to be cleared to go up a level
+func (g *Game) LinesToNextLevel() int {
+	return g.LevelGoal.LinesForLevel(g.Level) - g.GoalLines
+}
+
+// counts lines towards the level goal, going up as many levels as they are enough for
+func (g *Game) addGoalLines(lines int) {
+	g.GoalLines += lines
+	for g.GoalLines >= g.LevelGoal.LinesForLevel(g.Level) {
+		g.GoalLines -= g.LevelGoal.LinesForLevel(g.Level)
+		g.Level++
+	}
+}
+
+// how many rows the piece should fall over dt, taking soft drop into account,
+// at 20G or with a soft drop factor of 0 this is infinite so the piece goes straight to the bottom
+func (g *Game) gravityCells(dt time.Duration, softDrop bool) float64 {
+	cells := g.Gravity.CellsPerFrame(g.Level)
+	if softDrop {
+		if g.Handling.SoftDropFactor <= 0 {
+			return math.Inf(1)
+		}
+		cells *= float64(g.Handling.SoftDropFactor)
+	}
+	// the hidden rows make the board taller than 20, so 20 rows a frame wouldn't always reach the stack
+	if cells >= MaxGravity {
+		return math.Inf(1)
+	}
+	return cells * float64(dt) / float64(FrameDuration)
+}
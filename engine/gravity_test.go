@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// the seconds per row the guideline gives for the first 15 levels
+var guidelineSeconds = []float64{
+	1.00000, 0.79300, 0.61780, 0.47273, 0.35520, 0.26200, 0.18968, 0.13473,
+	0.09388, 0.06415, 0.04298, 0.02822, 0.01815, 0.01144, 0.00706,
+}
+
+func TestGuidelineGravity(t *testing.T) {
+	for i, want := range guidelineSeconds {
+		level := i + 1
+		seconds := 1 / (GravityGuideline.CellsPerFrame(level) * 60)
+		if math.Abs(seconds-want) > want*0.001 {
+			t.Errorf("level %d takes %.5f seconds a row, want %.5f", level, seconds, want)
+		}
+	}
+	// the formula is past 20G by level 20, and doesn't slow down again after it
+	for _, level := range []int{20, 25, 100} {
+		if cells := GravityGuideline.CellsPerFrame(level); cells != MaxGravity {
+			t.Errorf("level %d falls %v rows a frame, want %v", level, cells, float64(MaxGravity))
+		}
+	}
+}
+
+func TestNESGravity(t *testing.T) {
+	// frames per row on the NES, by the level here which is one more than the NES level
+	for level, frames := range map[int]int{1: 48, 2: 43, 9: 8, 10: 6, 11: 5, 14: 4, 17: 3, 19: 3, 20: 2, 29: 2, 30: 1, 50: 1} {
+		if cells := GravityNES.CellsPerFrame(level); cells != 1/float64(frames) {
+			t.Errorf("level %d falls a row every %v frames, want %d", level, 1/cells, frames)
+		}
+	}
+}
+
+func Test20GDropsToTheStack(t *testing.T) {
+	for _, level := range []int{1, 10, 30} {
+		if cells := Gravity20G.CellsPerFrame(level); cells != MaxGravity {
+			t.Errorf("level %d of 20G falls %v rows a frame", level, cells)
+		}
+	}
+
+	g := NewGame(1, NewBagRandomizer(1, 1))
+	g.Gravity = Gravity20G
+	for j := 0; j < g.PlayingBoard.Width; j++ {
+		g.PlayingBoard.Set(Point{0, j}, GarbagePixel)
+	}
+	g.PlayingBoard.Set(Point{1, 0}, GarbagePixel)
+	g.SetNextTetroFromBag()
+	g.Step(time.Millisecond, Input{})
+	if !g.CheckIfSomethingUnder(nil) {
+		t.Fatalf("the piece is at %v after one step of 20G, it should be on the stack", g.CurrentPiece.Shape)
+	}
+	if bottom := pieceBottom(&g); bottom > 2 {
+		t.Fatalf("the piece stopped with its bottom on row %d, above the stack", bottom)
+	}
+}
+
+func TestLevelGoals(t *testing.T) {
+	tests := []struct {
+		goal       LevelGoal
+		startLevel int
+
+		// lines cleared one lot after another, and the level and lines to the next level after each
+		lines  []int
+		levels []int
+		toNext []int
+	}{
+		{GoalFixed, 1, []int{9, 1, 25}, []int{1, 2, 4}, []int{1, 10, 5}},
+		{GoalFixed, 5, []int{10}, []int{6}, []int{10}},
+		// level 1 takes 5 lines, level 2 takes 10 and level 3 takes 15
+		{GoalVariable, 1, []int{4, 1, 12}, []int{1, 2, 3}, []int{1, 10, 13}},
+		{GoalVariable, 5, []int{24, 1}, []int{5, 6}, []int{1, 30}},
+	}
+	for _, tc := range tests {
+		g := NewGame(1, NewBagRandomizer(1, 1))
+		g.LevelGoal = tc.goal
+		g.SetStartLevel(tc.startLevel)
+		for i, lines := range tc.lines {
+			g.addGoalLines(lines)
+			if g.Level != tc.levels[i] || g.LinesToNextLevel() != tc.toNext[i] {
+				t.Errorf("%s from level %d: after %v lines at level %d with %d to go, want level %d with %d to go",
+					tc.goal, tc.startLevel, tc.lines[:i+1], g.Level, g.LinesToNextLevel(), tc.levels[i], tc.toNext[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package engine
+
+import "time"
+
+// Handling is how the piece responds to the keys being held, these are usually set by the player to taste
+type Handling struct {
+	// Delayed Auto Shift, how many milliseconds left or right has to be held before the piece starts moving on its own
+	DASMillis int `json:"das_millis"`
+
+	// Auto Repeat Rate, how many milliseconds between each move once DAS has charged,
+	// 0 moves the piece straight to the wall
+	ARRMillis int `json:"arr_millis"`
+
+	// Soft Drop Factor, how many times faster than gravity the piece falls while soft drop is held,
+	// 0 drops it straight to the bottom
+	SoftDropFactor int `json:"soft_drop_factor"`
+
+	// does holding left or right keep charging DAS while waiting for the next piece after a line clear,
+	// so the next piece can shift as soon as it appears
+	DASChargeDuringDelay bool `json:"das_charge_during_delay"`
+}
+
+// returns the handling the game starts with
+func DefaultHandling() Handling {
+	return Handling{
+		DASMillis:            167,
+		ARRMillis:            33,
+		SoftDropFactor:       20,
+		DASChargeDuringDelay: true,
+	}
+}
+
+// works out which way the piece is being shifted and how many times it should move this step,
+// moves is -1 if it should go all the way to the wall
+func (g *Game) updateShift(dt time.Duration, in, pressed Input) (dir int, moves int) {
+	// the direction pressed last wins when both are held, letting go of it goes back to the other one
+	switch {
+	case pressed.Left:
+		g.ShiftDirection = -1
+		g.DASTimer = 0
+		return -1, 1
+	case pressed.Right:
+		g.ShiftDirection = 1
+		g.DASTimer = 0
+		return 1, 1
+	case g.ShiftDirection == -1 && !in.Left, g.ShiftDirection == 1 && !in.Right:
+		g.ShiftDirection = 0
+		g.DASTimer = 0
+		if in.Left {
+			g.ShiftDirection = -1
+		} else if in.Right {
+			g.ShiftDirection = 1
+		}
+		return g.ShiftDirection, 0
+	}
+	if g.ShiftDirection == 0 {
+		return 0, 0
+	}
+
+	das := millis(g.Handling.DASMillis)
+	arr := millis(g.Handling.ARRMillis)
+	charged := g.DASTimer >= das
+	g.DASTimer += dt
+	if g.DASTimer < das {
+		return g.ShiftDirection, 0
+	}
+	if arr == 0 {
+		return g.ShiftDirection, -1
+	}
+
+	// the piece moves once as soon as DAS charges, and then every ARR after that
+	if !charged {
+		g.ARRTimer = g.DASTimer - das
+		moves = 1
+	} else {
+		g.ARRTimer += dt
+	}
+	for g.ARRTimer >= arr {
+		moves++
+		g.ARRTimer -= arr
+	}
+	return g.ShiftDirection, moves
+}
+
+// moves the current piece one pixel left if dir is -1 or right if it is 1
+func (g *Game) shiftPiece(dir int) bool {
+	if dir < 0 {
+		return g.MoveLeft()
+	}
+	return g.MoveRight()
+}
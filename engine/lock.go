@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+)
+
+// LockMode decides what gives a piece resting on the stack more time before it locks
+type LockMode string
+
+const (
+	// moving or rotating the piece resets the lock delay, but only MaxLockResets times,
+	// falling lower than the piece has been before gives it all its resets back
+	LockExtended LockMode = "extended"
+
+	// moving or rotating the piece always resets the lock delay, so it never has to lock
+	LockInfinite LockMode = "infinite"
+
+	// only falling resets the lock delay, moving or rotating on the stack doesn't help
+	LockClassic LockMode = "classic"
+)
+
+// the lock modes that can be used, for listing in flags and config errors
+var LockModes = []LockMode{LockExtended, LockInfinite, LockClassic}
+
+// checks that the lock mode is one the game knows
+func (m LockMode) Valid() error {
+	for _, mode := range LockModes {
+		if mode == m {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown lock mode %q, expected one of %v", m, LockModes)
+}
+
+// the lowest row any pixel of a shape is on
+func lowestRow(s Shape) int {
+	lowest := s[0].Row
+	for _, p := range s {
+		if p.Row < lowest {
+			lowest = p.Row
+		}
+	}
+	return lowest
+}
+
+// starts the lock delay over for a piece that just appeared
+func (g *Game) resetLock() {
+	g.LockTimer = 0
+	g.LockResets = 0
+	g.LowestRow = g.PlayingBoard.Height
+	if g.CurrentPiece != nil {
+		g.LowestRow = lowestRow(g.CurrentPiece.Shape)
+	}
+}
+
+// called whenever the current piece is moved left or right or rotated
+func (g *Game) pieceMoved() {
+	switch g.LockMode {
+	case LockExtended:
+		if g.LockResets < g.MaxLockResets {
+			g.LockTimer = 0
+		}
+		g.LockResets++
+	case LockInfinite:
+		g.LockTimer = 0
+	}
+}
+
+// called whenever the current piece falls a pixel
+func (g *Game) pieceFell() {
+	if g.LockMode == LockClassic {
+		g.LockTimer = 0
+	}
+	if lowest := lowestRow(g.CurrentPiece.Shape); lowest < g.LowestRow {
+		g.LowestRow = lowest
+		g.LockTimer = 0
+		g.LockResets = 0
+	}
+}
+
+// counts the lock delay down while the piece rests on something, returns true when it should lock,
+// in extended mode a piece that has used up its resets locks as soon as it touches the stack
+func (g *Game) lockDue(dt time.Duration) bool {
+	if !g.CheckIfSomethingUnder(nil) {
+		return false
+	}
+	if g.LockMode == LockExtended && g.LockResets > g.MaxLockResets {
+		return true
+	}
+	g.LockTimer += dt
+	return g.LockTimer >= millis(g.LockDelayMillis)
+}
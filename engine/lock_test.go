@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+// how long each half of a tap takes, well under the lock delay
+const tapTime = 100 * time.Millisecond
+
+// makes a game with an O resting on the floor of the board, along with a count of how many pieces have locked
+func newFloorGame(mode LockMode) (*Game, *int) {
+	g := NewGame(1, NewBagRandomizer(1, 1))
+	g.LockMode = mode
+	g.SetNextTetroFromBag()
+
+	// swapping whatever spawned for an O, which can slide back and forth without turning
+	g.CurrentPiece = Tetro(1).NewTetromino()
+	g.resetLock()
+	for g.GravityDrop() {
+	}
+
+	locks := 0
+	g.OnClear(func(ClearEvent) {
+		locks++
+	})
+	return &g, &locks
+}
+
+// presses a key for one step and lets go of it for the next
+func tap(g *Game, in Input) {
+	g.Step(tapTime, in)
+	g.Step(tapTime, Input{})
+}
+
+// taps left and right in turn, starting with left, n times
+func slide(g *Game, n int) {
+	for i := 0; i < n; i++ {
+		tap(g, Input{Left: i%2 == 0, Right: i%2 == 1})
+	}
+}
+
+func TestInfiniteLockNeverLocksWhileSliding(t *testing.T) {
+	g, locks := newFloorGame(LockInfinite)
+
+	slide(g, 100)
+	if *locks != 0 {
+		t.Fatalf("piece locked while sliding along the floor with infinite lock delay")
+	}
+
+	g.Step(time.Duration(g.LockDelayMillis)*time.Millisecond, Input{})
+	if *locks != 1 {
+		t.Fatalf("piece didn't lock once it stopped moving, locks = %d", *locks)
+	}
+}
+
+func TestExtendedLockStopsResettingAfterLimit(t *testing.T) {
+	g, locks := newFloorGame(LockExtended)
+
+	slide(g, g.MaxLockResets)
+	if *locks != 0 {
+		t.Fatalf("piece locked after %d moves, before using up its %d resets", g.MaxLockResets, g.MaxLockResets)
+	}
+
+	slide(g, 1)
+	if *locks != 1 {
+		t.Fatalf("piece didn't lock on the floor after using up its resets, locks = %d", *locks)
+	}
+}
+
+func TestExtendedLockResetsWhenFallingLower(t *testing.T) {
+	g, locks := newFloorGame(LockExtended)
+
+	// lifting the O onto a ledge one pixel high that covers the left of the board
+	g.CurrentPiece = Tetro(1).NewTetromino()
+	for j := 0; j <= 5; j++ {
+		g.PlayingBoard.Set(Point{0, j}, Pixel(3))
+	}
+	g.resetLock()
+	for g.GravityDrop() {
+	}
+
+	// using up nearly every reset on the ledge, then sliding off the end of it
+	slide(g, g.MaxLockResets-1)
+	tap(g, Input{Right: true})
+	tap(g, Input{Right: true})
+	tap(g, Input{SoftDrop: true})
+	if *locks != 0 {
+		t.Fatalf("piece locked before falling off the ledge")
+	}
+
+	// falling to the floor gave the piece all its resets back
+	for i := 0; i < g.MaxLockResets; i++ {
+		tap(g, Input{Right: i%2 == 0, Left: i%2 == 1})
+	}
+	if *locks != 0 {
+		t.Fatalf("piece locked on the floor before using up the resets it got back by falling")
+	}
+}
+
+func TestClassicLockIgnoresSliding(t *testing.T) {
+	g, locks := newFloorGame(LockClassic)
+
+	slide(g, 2)
+	if *locks != 0 {
+		t.Fatalf("piece locked before the lock delay was up")
+	}
+
+	slide(g, 1)
+	if *locks != 1 {
+		t.Fatalf("sliding along the floor reset the classic lock delay, locks = %d", *locks)
+	}
+}
+
+func TestClassicLockResetsWhenFalling(t *testing.T) {
+	g, locks := newFloorGame(LockClassic)
+
+	// starting the O high up so it can soft drop one pixel at a time
+	g.CurrentPiece = Tetro(1).NewTetromino()
+	g.resetLock()
+
+	for i := 0; i < 10; i++ {
+		tap(g, Input{SoftDrop: true})
+	}
+	if *locks != 0 {
+		t.Fatalf("piece locked while it was still falling")
+	}
+}
@@ -0,0 +1,189 @@
+package engine
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Mode is the set of rules a game is played to on top of the usual ones, mostly deciding when it is won
+type Mode interface {
+	// the name of the mode, this is how it is picked and saved in replays and the high score table
+	Name() string
+
+	// sets up the board for the mode, this is called by Game.SetMode before the first piece spawns
+	Start(g *Game)
+
+	// called at the end of every step while the game is still going,
+	// a mode with a goal sets Game.Finished once the goal has been reached
+	Update(g *Game)
+}
+
+// the modes that can be passed to NewMode
+var ModeNames = []string{"marathon", "sprint", "ultra", "ultra2", "dig"}
+
+// makes the mode with the given name
+func NewMode(name string) (Mode, error) {
+	switch name {
+	case "marathon":
+		return Marathon{}, nil
+	case "sprint":
+		return NewSprint(), nil
+	case "ultra":
+		return NewUltra(3 * time.Minute), nil
+	case "ultra2":
+		return NewUltra(2 * time.Minute), nil
+	case "dig":
+		return NewDig(), nil
+	}
+	return nil, fmt.Errorf("unknown mode %q, expected one of %v", name, ModeNames)
+}
+
+// Marathon goes on until the stack reaches the top, getting faster every level
+type Marathon struct{}
+
+// the name of the mode
+func (Marathon) Name() string {
+	return "marathon"
+}
+
+// a marathon starts with an empty board
+func (Marathon) Start(g *Game) {}
+
+// a marathon never finishes, it only ends when the game is over
+func (Marathon) Update(g *Game) {}
+
+// Sprint is a race to clear a number of lines as fast as possible
+type Sprint struct {
+	// how many lines have to be cleared to finish
+	Lines int
+
+	// a split time is taken every time this many more lines have been cleared, 0 for no splits
+	SplitLines int
+
+	// how long it took to reach each split, the last one being the time the sprint finished in
+	Splits []time.Duration
+}
+
+// returns the usual 40 line sprint, with a split every 10 lines
+func NewSprint() *Sprint {
+	return &Sprint{
+		Lines:      40,
+		SplitLines: 10,
+	}
+}
+
+// the name of the mode
+func (s *Sprint) Name() string {
+	return "sprint"
+}
+
+// a sprint starts with an empty board
+func (s *Sprint) Start(g *Game) {}
+
+// takes the split times and finishes the game once enough lines have been cleared
+func (s *Sprint) Update(g *Game) {
+	for s.SplitLines > 0 && len(s.Splits) < s.Lines/s.SplitLines && g.LinesCleared >= (len(s.Splits)+1)*s.SplitLines {
+		s.Splits = append(s.Splits, g.PlayTime)
+	}
+	if g.LinesCleared >= s.Lines {
+		g.Finished = true
+	}
+}
+
+// how many lines are left to clear
+func (s *Sprint) LinesLeft(g *Game) int {
+	if g.LinesCleared >= s.Lines {
+		return 0
+	}
+	return s.Lines - g.LinesCleared
+}
+
+// Ultra is a race to score as much as possible before the time runs out
+type Ultra struct {
+	// how long the game lasts
+	Duration time.Duration
+}
+
+// returns an ultra that lasts for d
+func NewUltra(d time.Duration) *Ultra {
+	return &Ultra{Duration: d}
+}
+
+// the name of the mode, the usual 3 minute ultra is just "ultra" and any other length has its minutes after it
+func (u *Ultra) Name() string {
+	if u.Duration == 3*time.Minute {
+		return "ultra"
+	}
+	return fmt.Sprintf("ultra%d", int(u.Duration.Minutes()))
+}
+
+// an ultra starts with an empty board
+func (u *Ultra) Start(g *Game) {}
+
+// finishes the game once the time is up, the clock stops at exactly the duration
+func (u *Ultra) Update(g *Game) {
+	if g.PlayTime >= u.Duration {
+		g.PlayTime = u.Duration
+		g.Finished = true
+	}
+}
+
+// how long is left before the time runs out
+func (u *Ultra) TimeLeft(g *Game) time.Duration {
+	if g.PlayTime >= u.Duration {
+		return 0
+	}
+	return u.Duration - g.PlayTime
+}
+
+// Dig is a race to clear away rows of garbage the board starts with
+type Dig struct {
+	// how many rows of garbage the board starts with
+	Rows int
+}
+
+// returns a dig with 10 rows of garbage
+func NewDig() *Dig {
+	return &Dig{Rows: 10}
+}
+
+// the name of the mode
+func (d *Dig) Name() string {
+	return "dig"
+}
+
+// fills the bottom of the board with messy garbage, the holes come from the seed of the game
+// so the same seed always gives the same garbage
+func (d *Dig) Start(g *Game) {
+	g.AddMessyGarbage(d.Rows, rand.New(rand.NewSource(g.Seed)))
+}
+
+// finishes the game once every row of garbage has been cleared
+func (d *Dig) Update(g *Game) {
+	if g.GarbageLeft() == 0 {
+		g.Finished = true
+	}
+}
+
+// sets the rules the game is played to and sets up the board for them,
+// this should be called before the first piece spawns
+func (g *Game) SetMode(m Mode) {
+	g.Mode = m
+	m.Start(g)
+}
+
+// lets the mode know about the step that just happened, unless the game was lost in it
+func (g *Game) updateMode() {
+	if g.Mode != nil && !g.GameOver {
+		g.Mode.Update(g)
+	}
+}
+
+// how many pieces have been locked each second on average
+func (g *Game) PiecesPerSecond() float64 {
+	if g.PlayTime <= 0 {
+		return 0
+	}
+	return float64(g.PiecesPlaced) / g.PlayTime.Seconds()
+}
@@ -0,0 +1,89 @@
+package engine
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// a sprint takes a split every 10 lines and finishes at 40, each tetris here takes a second
+func TestSprint(t *testing.T) {
+	g := newBoardGame(nil)
+	g.SetMode(NewSprint())
+	sprint := g.Mode.(*Sprint)
+	for i := 1; i <= 10; i++ {
+		if g.Finished {
+			t.Fatalf("the sprint finished after %d lines", g.LinesCleared)
+		}
+		for row := 0; row < 4; row++ {
+			for col := 0; col < g.PlayingBoard.Width-1; col++ {
+				g.PlayingBoard.Set(Point{row, col}, GarbagePixel)
+			}
+		}
+		place(t, &g, placement{Tetro(4), "CRRRRRD"})
+		g.Step(time.Second, Input{})
+		if g.LinesCleared != 4*i {
+			t.Fatalf("%d lines after %d tetrises", g.LinesCleared, i)
+		}
+	}
+
+	// the splits are taken on the step the lines went past 10, 20, 30 and 40
+	want := []time.Duration{3 * time.Second, 5 * time.Second, 8 * time.Second, 10 * time.Second}
+	if !g.Finished || g.GameOver || !reflect.DeepEqual(sprint.Splits, want) {
+		t.Fatalf("finished %v, game over %v, splits %v, want finished with splits %v", g.Finished, g.GameOver, sprint.Splits, want)
+	}
+	if left := sprint.LinesLeft(&g); left != 0 {
+		t.Fatalf("%d lines left after finishing", left)
+	}
+}
+
+// an ultra finishes when its time is up, with the clock stopped at exactly its length, and the game isn't lost
+func TestUltra(t *testing.T) {
+	g := NewGame(1, NewBagRandomizer(1, 1))
+	g.SetMode(NewUltra(2 * time.Minute))
+	g.SetNextTetroFromBag()
+	ultra := g.Mode.(*Ultra)
+	if ultra.Name() != "ultra2" {
+		t.Fatalf("a 2 minute ultra is called %q", ultra.Name())
+	}
+
+	const dt = 700 * time.Millisecond
+	for g.PlayTime+dt < ultra.Duration {
+		g.Step(dt, Input{})
+		if g.Finished || g.GameOver {
+			t.Fatalf("the ultra ended %v into it", g.PlayTime)
+		}
+	}
+	g.Step(dt, Input{})
+	if !g.Finished || g.GameOver || g.PlayTime != ultra.Duration || ultra.TimeLeft(&g) != 0 {
+		t.Fatalf("finished %v, game over %v, play time %v, %v left, want finished at %v", g.Finished, g.GameOver, g.PlayTime, ultra.TimeLeft(&g), ultra.Duration)
+	}
+
+	// nothing happens once the game has finished
+	g.Step(dt, Input{})
+	if g.PlayTime != ultra.Duration {
+		t.Fatalf("the clock kept going to %v", g.PlayTime)
+	}
+}
+
+// a sprint saved without splits carries on without taking any
+func TestResumeSprintWithoutSplits(t *testing.T) {
+	g := NewGame(1, NewBagRandomizer(1, 1))
+	g.SetMode(NewSprint())
+	g.SetNextTetroFromBag()
+	s, err := g.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.ModeState = json.RawMessage(`{"Lines": 1, "SplitLines": 0}`)
+	resumed, err := s.Resume()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resumed.LinesCleared = 1
+	resumed.Step(time.Millisecond, Input{})
+	if sprint := resumed.Mode.(*Sprint); !resumed.Finished || len(sprint.Splits) != 0 {
+		t.Fatalf("the sprint finished %v with splits %v, want finished with none", resumed.Finished, sprint.Splits)
+	}
+}
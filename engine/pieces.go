@@ -0,0 +1,222 @@
+package engine
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"io/fs"
+	"os"
+)
+
+// the piece sets that come with the game, one file per set
+//
+//go:embed pieces/*.json
+var pieceFiles embed.FS
+
+// the names of the piece sets that come with the game, LoadPieceSet also takes the path of a piece set file
+var PieceSetNames = []string{"tetrominoes", "pentominoes", "big", "small"}
+
+// the colors of the pixels that aren't part of a piece
+var (
+	EmptyColor   = color.RGBA{49, 50, 68, 255}
+	GhostColor   = color.RGBA{166, 173, 200, 255}
+	GarbageColor = color.RGBA{108, 112, 134, 255}
+)
+
+// Piece is one kind of piece in a piece set, as it is written in a piece set file
+type Piece struct {
+	Name string `json:"name"`
+
+	// the color the piece is drawn in, as "#rrggbb"
+	Color string `json:"color"`
+
+	// the piece facing the way it spawns, one string per row from the top down with an X for each pixel of the piece,
+	// the rows and columns around it make up the box it spawns in, the top of the box is the top of the board
+	Shape []string `json:"shape"`
+
+	// the point the piece rotates around as [row, col] in half pixels, counted from the middle of the bottom left pixel
+	// of its box, both even for the middle of a pixel or both odd for the corner between pixels
+	Pivot [2]int `json:"pivot"`
+
+	// the wall kicks it uses when it rotates, "srs" (the default) for the J, L, S, T and Z kicks, "srs-i" for the I kicks,
+	// "srs-2x" and "srs-i-2x" for the same kicks twice as far for pieces twice the size, or "none" to only rotate in place
+	Kicks string `json:"kicks"`
+
+	// does it score t-spins, found with the 3 corner rule around its pivot
+	Spins bool `json:"spins,omitempty"`
+
+	// worked out from the fields above when the set is loaded, the pixels of the shape from the bottom left of its box
+	cells  Shape
+	width  int
+	height int
+	color  color.RGBA
+}
+
+// PieceSet is every kind of piece a game can be dealt, a Tetro is the place of a piece in the set, starting at 1
+type PieceSet struct {
+	Name   string  `json:"name"`
+	Pieces []Piece `json:"pieces"`
+}
+
+// Tetrominoes is the standard set of 7, in the order of the Tetro numbers the rest of the game uses for them
+var Tetrominoes *PieceSet
+
+// loads the standard set, this is done in init rather than where it is declared because loading it uses the kick tables,
+// which the compiler can't see through json.Unmarshal to make sure they are ready first
+func init() {
+	var err error
+	if Tetrominoes, err = LoadPieceSet("tetrominoes"); err != nil {
+		panic(err)
+	}
+}
+
+// loads one of the piece sets that come with the game by name, or a piece set file if name isn't one of them
+func LoadPieceSet(name string) (*PieceSet, error) {
+	data, err := pieceFiles.ReadFile("pieces/" + name + ".json")
+	if errors.Is(err, fs.ErrNotExist) {
+		data, err = os.ReadFile(name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unknown piece set %q, expected one of %v or a piece set file: %w", name, PieceSetNames, err)
+	}
+	s := &PieceSet{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("reading piece set %s: %w", name, err)
+	}
+	return s, nil
+}
+
+// reads a piece set and works out the shape and color of each piece, so it can be stored in replays and saves
+func (s *PieceSet) UnmarshalJSON(data []byte) error {
+	// the alias doesn't have this method, so decoding into it doesn't come back here
+	type pieceSet PieceSet
+	if err := json.Unmarshal(data, (*pieceSet)(s)); err != nil {
+		return err
+	}
+	if len(s.Pieces) == 0 {
+		return fmt.Errorf("piece set %q has no pieces", s.Name)
+	}
+	for i := range s.Pieces {
+		if err := s.Pieces[i].prepare(); err != nil {
+			return fmt.Errorf("piece %d (%s) of %q: %w", i+1, s.Pieces[i].Name, s.Name, err)
+		}
+	}
+	return nil
+}
+
+// checks the piece makes sense and works out its pixels and color
+func (p *Piece) prepare() error {
+	p.height = len(p.Shape)
+	if p.height == 0 {
+		return errors.New("the shape has no rows")
+	}
+	p.width = len(p.Shape[0])
+	p.cells = nil
+	for i, row := range p.Shape {
+		if len(row) != p.width {
+			return fmt.Errorf("row %d of the shape is %d wide, the first row is %d", i+1, len(row), p.width)
+		}
+		for j, c := range row {
+			switch c {
+			case 'X':
+				p.cells = append(p.cells, Point{Row: p.height - 1 - i, Col: j})
+			case '.':
+			default:
+				return fmt.Errorf("the shape has %q in it, only X and . can be used", c)
+			}
+		}
+	}
+	if len(p.cells) == 0 {
+		return errors.New("the shape has no pixels")
+	}
+	if p.width > MaxBoardWidth {
+		return fmt.Errorf("the shape is %d wide, it can't be wider than %d", p.width, MaxBoardWidth)
+	}
+	if (p.Pivot[0]-p.Pivot[1])%2 != 0 {
+		return fmt.Errorf("the pivot %v isn't in the middle of a pixel or on a corner", p.Pivot)
+	}
+	if p.Kicks == "" {
+		p.Kicks = "srs"
+	}
+	if _, ok := kickTables[p.Kicks]; !ok {
+		return fmt.Errorf("unknown kicks %q, expected one of %v", p.Kicks, kickNames)
+	}
+	if _, err := fmt.Sscanf(p.Color, "#%02x%02x%02x", &p.color.R, &p.color.G, &p.color.B); err != nil || len(p.Color) != 7 {
+		return fmt.Errorf("the color %q isn't written as #rrggbb", p.Color)
+	}
+	p.color.A = 255
+	return nil
+}
+
+// how many kinds of piece there are in the set
+func (s *PieceSet) Len() int {
+	return len(s.Pieces)
+}
+
+// gets a piece of the set
+func (s *PieceSet) Piece(t Tetro) *Piece {
+	if t < 1 || int(t) > len(s.Pieces) {
+		panic(fmt.Sprintf("Invalid integer passed into Piece: %v", t))
+	}
+	return &s.Pieces[t-1]
+}
+
+// the pixels of a piece facing the way it spawns, counted from the bottom left of its box,
+// along with how wide and tall the box is, for showing the piece next to the board
+func (s *PieceSet) Preview(t Tetro) (Shape, int, int) {
+	p := s.Piece(t)
+	return append(Shape(nil), p.cells...), p.width, p.height
+}
+
+// how tall the tallest box of any piece in the set is
+func (s *PieceSet) MaxHeight() int {
+	height := 0
+	for _, p := range s.Pieces {
+		if p.height > height {
+			height = p.height
+		}
+	}
+	return height
+}
+
+// makes a new piece where it spawns on a board width wide and height tall, with its box at the top of the board,
+// in the middle, or as close to the middle as fits
+func (s *PieceSet) NewTetromino(t Tetro, width, height int) *Tetromino {
+	p := s.Piece(t)
+	left := width/2 - 1
+	if left > width-p.width {
+		left = width - p.width
+	}
+	if left < 0 {
+		left = 0
+	}
+	bottom := height - p.height
+
+	shape := make(Shape, len(p.cells))
+	for i, c := range p.cells {
+		shape[i] = Point{Row: c.Row + bottom, Col: c.Col + left}
+	}
+	return &Tetromino{
+		Shape:    shape,
+		Tetro:    t,
+		Rotation: RotationSpawn,
+		Pivot:    Point{Row: 2*bottom + p.Pivot[0], Col: 2*left + p.Pivot[1]},
+		Kicks:    p.Kicks,
+	}
+}
+
+// the color to draw a pixel of the board in
+func (s *PieceSet) Color(pixel Pixel) color.RGBA {
+	switch {
+	case pixel == Pixel(0):
+		return EmptyColor
+	case pixel == GhostPixel:
+		return GhostColor
+	case pixel < 0 || int(pixel) > len(s.Pieces):
+		// garbage, or a piece from a set this one doesn't know, like the board of someone playing with another set
+		return GarbageColor
+	}
+	return s.Pieces[pixel-1].color
+}
@@ -0,0 +1,165 @@
+package engine
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// plays every piece set on a few board sizes, moving and turning each piece at random before dropping it,
+// checking the pieces never leave the board
+func TestPieceSetsPlay(t *testing.T) {
+	sizes := [][2]int{{10, 20}, {8, 12}, {16, 30}}
+	for _, name := range PieceSetNames {
+		pieces, err := LoadPieceSet(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, size := range sizes {
+			if err := CheckBoardSize(size[0], size[1], pieces); err != nil {
+				t.Fatalf("%s on %dx%d: %v", name, size[0], size[1], err)
+			}
+			rng := rand.New(rand.NewSource(1))
+			randomizer, err := NewRandomizer("7bag", 1, pieces.Len())
+			if err != nil {
+				t.Fatal(err)
+			}
+			g := NewGame(1, randomizer)
+			g.Pieces = pieces
+			g.SetBoardSize(size[0], size[1])
+			g.SetNextTetroFromBag()
+
+			for n := 0; n < 300 && !g.GameOver; n++ {
+				for k := rng.Intn(6); k > 0; k-- {
+					switch rng.Intn(4) {
+					case 0:
+						g.MoveLeft()
+					case 1:
+						g.MoveRight()
+					case 2:
+						g.RotateClockWise()
+					case 3:
+						g.RotateCounterClockWise()
+					}
+				}
+				for _, p := range g.CurrentPiece.Shape {
+					if !g.PlayingBoard.inside(p) {
+						t.Fatalf("%s on %dx%d: piece %d is outside the board at %v", name, size[0], size[1], g.CurrentPiece.Tetro, p)
+					}
+				}
+				g.HardDrop()
+			}
+		}
+	}
+}
+
+func TestCheckBoardSize(t *testing.T) {
+	big, err := LoadPieceSet("big")
+	if err != nil {
+		t.Fatal(err)
+	}
+	small, err := LoadPieceSet("small")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckBoardSize(6, 20, big); err == nil {
+		t.Fatal("the big I fits on a board 6 wide")
+	}
+	if err := CheckBoardSize(MaxBoardWidth+1, 20, Tetrominoes); err == nil {
+		t.Fatalf("a board %d wide is allowed", MaxBoardWidth+1)
+	}
+	if err := CheckBoardSize(MinBoardWidth-1, 20, small); err == nil {
+		t.Fatalf("a board %d wide is allowed", MinBoardWidth-1)
+	}
+}
+
+// drops a pentomino I standing up into a well down the left of 5 rows that are full apart from it,
+// with something left over above the well when leftover is true so it isn't a perfect clear
+func clearFiveLines(t *testing.T, leftover bool) ClearEvent {
+	t.Helper()
+	pieces, err := LoadPieceSet("pentominoes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGame(1, NewBagRandomizer(1, 1))
+	g.Pieces = pieces
+	for i := 0; i < 5; i++ {
+		for j := 1; j < g.PlayingBoard.Width; j++ {
+			g.PlayingBoard.Set(Point{i, j}, GarbagePixel)
+		}
+	}
+	if leftover {
+		g.PlayingBoard.Set(Point{5, 9}, GarbagePixel)
+	}
+	// the I is the third pentomino, it spawns lying flat on the top row so it falls a little before there's room to stand it up
+	g.CurrentPiece = g.newPiece(3)
+	g.GravityDrop()
+	g.GravityDrop()
+	if !g.RotateClockWise() {
+		t.Fatal("the I couldn't turn")
+	}
+	for g.MoveLeft() {
+	}
+
+	var event ClearEvent
+	g.OnClear(func(e ClearEvent) {
+		event = e
+	})
+	g.HardDrop()
+	if event.Lines != 5 {
+		t.Fatalf("the I cleared %d lines, want 5", event.Lines)
+	}
+	if g.PlayingBoard.Empty() == leftover {
+		t.Fatalf("the board is empty: %v, want %v", g.PlayingBoard.Empty(), !leftover)
+	}
+	return event
+}
+
+func TestClearFiveLines(t *testing.T) {
+	event := clearFiveLines(t, true)
+	if event.PerfectClear {
+		t.Fatal("clearing under a leftover pixel was a perfect clear")
+	}
+	if labels := event.Labels(); len(labels) == 0 || labels[0] != "5 LINES" {
+		t.Fatalf("5 lines are labelled %v", labels)
+	}
+	// scored like a tetris, along with the hard drop
+	if event.Points != 800 {
+		t.Fatalf("5 lines scored %d, want 800", event.Points)
+	}
+
+	event = clearFiveLines(t, false)
+	if !event.PerfectClear {
+		t.Fatal("clearing the whole board wasn't a perfect clear")
+	}
+	if event.Points != 800+2000 {
+		t.Fatalf("a 5 line perfect clear scored %d, want %d", event.Points, 800+2000)
+	}
+}
+
+func TestBigKicksAreDoubled(t *testing.T) {
+	for _, kicks := range [][2]string{{"srs", "srs-2x"}, {"srs-i", "srs-i-2x"}} {
+		for from := RotationSpawn; from <= RotationLeft; from++ {
+			for turns := 1; turns <= 3; turns++ {
+				to := (from + Rotation(turns)) % 4
+				normal, big := kicksFor(kicks[0], from, to), kicksFor(kicks[1], from, to)
+				if len(normal) != len(big) {
+					t.Fatalf("%s has %d kicks from %d to %d, %s has %d", kicks[1], len(big), from, to, kicks[0], len(normal))
+				}
+				for i := range normal {
+					if big[i] != (Point{2 * normal[i].Row, 2 * normal[i].Col}) {
+						t.Fatalf("kick %d of %s from %d to %d is %v, want twice %v", i, kicks[1], from, to, big[i], normal[i])
+					}
+				}
+			}
+		}
+	}
+	big, err := LoadPieceSet("big")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range big.Pieces {
+		if p.Kicks != "none" && p.Kicks != "srs-2x" && p.Kicks != "srs-i-2x" {
+			t.Fatalf("big %s kicks with %s", p.Name, p.Kicks)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package engine
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Randomizer decides which tetro comes next, the same seed always gives the same order of tetros
+type Randomizer interface {
+	// gets the next tetro
+	Next() Tetro
+
+	// the name of the randomizer, this is what NewRandomizer takes to make it again
+	Name() string
+
+	// where the randomizer is up to, RestoreRandomizer carries on from here
+	State() RandomizerState
+}
+
+// RandomizerState is where a randomizer is up to, so a saved game can carry on dealing the same tetros
+type RandomizerState struct {
+	// the name of the randomizer and how many kinds of tetro it picks from, as passed to NewRandomizer
+	Name   string `json:"name"`
+	Pieces int    `json:"pieces"`
+
+	// how many numbers have been taken from its random source since it was seeded
+	Draws uint64 `json:"draws"`
+
+	// what is left in the bag of a bag randomizer
+	Bag []Tetro `json:"bag,omitempty"`
+
+	// the history of a history randomizer and whether it has dealt its first tetro yet
+	History []Tetro `json:"history,omitempty"`
+	First   bool    `json:"first,omitempty"`
+}
+
+// makes a randomizer again from a state it was in, seed being the seed it was first made with,
+// it deals the same tetros from then on as the randomizer the state was taken from
+func RestoreRandomizer(seed int64, state RandomizerState) (Randomizer, error) {
+	r, err := NewRandomizer(state.Name, seed, state.Pieces)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTetros(state.Bag, 1, state.Pieces); err != nil {
+		return nil, err
+	}
+	// the history of a piece set other than the standard one starts out empty, 0 being no tetro
+	if err := checkTetros(state.History, 0, state.Pieces); err != nil {
+		return nil, err
+	}
+	switch r := r.(type) {
+	case *BagRandomizer:
+		r.src.skip(state.Draws)
+		r.bag = append([]Tetro(nil), state.Bag...)
+	case *PureRandomizer:
+		r.src.skip(state.Draws)
+	case *HistoryRandomizer:
+		if len(state.History) != len(r.history) {
+			return nil, fmt.Errorf("randomizer state has a history of %d tetros, expected %d", len(state.History), len(r.history))
+		}
+		r.src.skip(state.Draws)
+		r.history = append([]Tetro(nil), state.History...)
+		r.first = state.First
+	}
+	return r, nil
+}
+
+// checks every tetro in a randomizer state is at least least and one of the pieces kinds there are
+func checkTetros(list []Tetro, least Tetro, pieces int) error {
+	for _, t := range list {
+		if t < least || int(t) > pieces {
+			return fmt.Errorf("randomizer state has tetro %d in it, there are only %d", t, pieces)
+		}
+	}
+	return nil
+}
+
+// countingSource is a random source that counts how many numbers have been taken from it,
+// so it can be put back where it was by seeding it again and taking the same number
+type countingSource struct {
+	src   rand.Source64
+	draws uint64
+}
+
+func newCountingSource(seed int64) *countingSource {
+	return &countingSource{src: rand.NewSource(seed).(rand.Source64)}
+}
+
+func (s *countingSource) Int63() int64 {
+	s.draws++
+	return s.src.Int63()
+}
+
+func (s *countingSource) Uint64() uint64 {
+	s.draws++
+	return s.src.Uint64()
+}
+
+func (s *countingSource) Seed(seed int64) {
+	s.src.Seed(seed)
+	s.draws = 0
+}
+
+// takes numbers until draws have been taken since it was seeded
+func (s *countingSource) skip(draws uint64) {
+	for s.draws < draws {
+		s.Uint64()
+	}
+}
+
+// the names of the randomizers that can be passed to NewRandomizer
+var RandomizerNames = []string{"7bag", "14bag", "random", "history"}
+
+// makes a randomizer from its name, seeded with seed, picking from a piece set with pieces kinds of piece in it,
+// the bags are named after the standard set but hold every piece of the set
+func NewRandomizer(name string, seed int64, pieces int) (Randomizer, error) {
+	switch name {
+	case "7bag":
+		b := NewBagRandomizer(seed, 1)
+		b.Pieces = pieces
+		return b, nil
+	case "14bag":
+		b := NewBagRandomizer(seed, 2)
+		b.Pieces = pieces
+		return b, nil
+	case "random":
+		p := NewPureRandomizer(seed)
+		p.Pieces = pieces
+		return p, nil
+	case "history":
+		h := NewHistoryRandomizer(seed, 6)
+		if pieces != Tetrominoes.Len() {
+			// starting with S and Z in the history and no overhang first only make sense for the standard set
+			h.Pieces = pieces
+			h.history = make([]Tetro, len(h.history))
+			h.first = false
+		}
+		return h, nil
+	}
+	return nil, fmt.Errorf("unknown randomizer %q, expected one of %v", name, RandomizerNames)
+}
+
+// BagRandomizer shuffles a bag with every tetro in it and deals it out before shuffling a new one,
+// with one copy of each tetro this is the usual 7-bag
+type BagRandomizer struct {
+	// how many of each tetro go in the bag
+	Copies int
+
+	// how many kinds of tetro there are, 7 unless the game uses another piece set
+	Pieces int
+
+	r   *rand.Rand
+	src *countingSource
+	bag []Tetro
+}
+
+// makes a bag randomizer with copies of each tetro in the bag
+func NewBagRandomizer(seed int64, copies int) *BagRandomizer {
+	src := newCountingSource(seed)
+	return &BagRandomizer{
+		Copies: copies,
+		Pieces: 7,
+		r:      rand.New(src),
+		src:    src,
+	}
+}
+
+func (b *BagRandomizer) Next() Tetro {
+	if len(b.bag) == 0 {
+		for i := 0; i < b.Copies; i++ {
+			for t := Tetro(1); t <= Tetro(b.Pieces); t++ {
+				b.bag = append(b.bag, t)
+			}
+		}
+		b.r.Shuffle(len(b.bag), func(i, j int) {
+			b.bag[i], b.bag[j] = b.bag[j], b.bag[i]
+		})
+	}
+	t := b.bag[0]
+	b.bag = b.bag[1:]
+	return t
+}
+
+func (b *BagRandomizer) Name() string {
+	return fmt.Sprintf("%dbag", 7*b.Copies)
+}
+
+func (b *BagRandomizer) State() RandomizerState {
+	return RandomizerState{Name: b.Name(), Pieces: b.Pieces, Draws: b.src.draws, Bag: append([]Tetro(nil), b.bag...)}
+}
+
+// PureRandomizer picks every tetro at random with nothing stopping long droughts or floods
+type PureRandomizer struct {
+	// how many kinds of tetro there are, 7 unless the game uses another piece set
+	Pieces int
+
+	r   *rand.Rand
+	src *countingSource
+}
+
+// makes a pure randomizer
+func NewPureRandomizer(seed int64) *PureRandomizer {
+	src := newCountingSource(seed)
+	return &PureRandomizer{Pieces: 7, r: rand.New(src), src: src}
+}
+
+func (p *PureRandomizer) Next() Tetro {
+	return Tetro(p.r.Intn(p.Pieces) + 1)
+}
+
+func (p *PureRandomizer) Name() string {
+	return "random"
+}
+
+func (p *PureRandomizer) State() RandomizerState {
+	return RandomizerState{Name: p.Name(), Pieces: p.Pieces, Draws: p.src.draws}
+}
+
+// HistoryRandomizer is the TGM style randomizer, it remembers the last 4 tetros
+// and rerolls a tetro that is in the history up to Rolls times before giving up and using it anyway
+type HistoryRandomizer struct {
+	// how many times to roll before accepting a tetro that is in the history
+	Rolls int
+
+	// how many kinds of tetro there are, 7 unless the game uses another piece set
+	Pieces int
+
+	r       *rand.Rand
+	src     *countingSource
+	history []Tetro
+	first   bool
+}
+
+// makes a history randomizer, TGM uses 4 rolls and TGM2 uses 6
+func NewHistoryRandomizer(seed int64, rolls int) *HistoryRandomizer {
+	src := newCountingSource(seed)
+	return &HistoryRandomizer{
+		Rolls:  rolls,
+		Pieces: 7,
+		r:      rand.New(src),
+		src:    src,
+		// the history starts full of S and Z so they are unlikely to come early
+		history: []Tetro{7, 6, 6, 7},
+		first:   true,
+	}
+}
+
+func (h *HistoryRandomizer) Next() Tetro {
+	var t Tetro
+	if h.first {
+		// the first tetro is never an S, Z or O so the game never starts with an overhang
+		h.first = false
+		t = []Tetro{2, 3, 4, 5}[h.r.Intn(4)]
+	} else {
+		for i := 0; i < h.Rolls; i++ {
+			t = Tetro(h.r.Intn(h.Pieces) + 1)
+			if !containsTetro(h.history, t) {
+				break
+			}
+		}
+	}
+	h.history = append(h.history[1:], t)
+	return t
+}
+
+func (h *HistoryRandomizer) Name() string {
+	return "history"
+}
+
+func (h *HistoryRandomizer) State() RandomizerState {
+	return RandomizerState{
+		Name:    h.Name(),
+		Pieces:  h.Pieces,
+		Draws:   h.src.draws,
+		History: append([]Tetro(nil), h.history...),
+		First:   h.first,
+	}
+}
+
+// checks if a tetro is in a list of tetros
+func containsTetro(list []Tetro, t Tetro) bool {
+	for _, v := range list {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+)
+
+// deals n tetros from the randomizer
+func deal(r Randomizer, n int) []Tetro {
+	tetros := make([]Tetro, n)
+	for i := range tetros {
+		tetros[i] = r.Next()
+	}
+	return tetros
+}
+
+func TestSameSeedSameOrder(t *testing.T) {
+	for _, name := range RandomizerNames {
+		a, err := NewRandomizer(name, 42, Tetrominoes.Len())
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := NewRandomizer(name, 42, Tetrominoes.Len())
+		c, _ := NewRandomizer(name, 43, Tetrominoes.Len())
+		first, second, other := deal(a, 100), deal(b, 100), deal(c, 100)
+		if !reflect.DeepEqual(first, second) {
+			t.Errorf("%s dealt two orders from the same seed:\n%v\n%v", name, first, second)
+		}
+		if reflect.DeepEqual(first, other) {
+			t.Errorf("%s dealt the same order from different seeds", name)
+		}
+	}
+}
+
+// checks every run of copies*7 tetros dealt from the start has copies of each tetro
+func checkBags(t *testing.T, name string, copies int) {
+	r, err := NewRandomizer(name, 7, Tetrominoes.Len())
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := 7 * copies
+	for bag := 0; bag < 20; bag++ {
+		counts := map[Tetro]int{}
+		for _, tetro := range deal(r, size) {
+			counts[tetro]++
+		}
+		for tetro := Tetro(1); tetro <= 7; tetro++ {
+			if counts[tetro] != copies {
+				t.Fatalf("%s bag %d has %d of tetro %d, want %d: %v", name, bag, counts[tetro], tetro, copies, counts)
+			}
+		}
+	}
+}
+
+func TestSevenBag(t *testing.T) {
+	checkBags(t, "7bag", 1)
+}
+
+func TestFourteenBag(t *testing.T) {
+	checkBags(t, "14bag", 2)
+}
+
+func TestBagOfAnotherSet(t *testing.T) {
+	r, err := NewRandomizer("7bag", 1, 18)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[Tetro]bool{}
+	for _, tetro := range deal(r, 18) {
+		seen[tetro] = true
+	}
+	if len(seen) != 18 {
+		t.Fatalf("a bag of 18 pieces dealt %d different ones", len(seen))
+	}
+}
+
+func TestHistoryFirstPiece(t *testing.T) {
+	for seed := int64(0); seed < 200; seed++ {
+		r, err := NewRandomizer("history", seed, Tetrominoes.Len())
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch first := r.Next(); first {
+		case 1, 6, 7:
+			t.Fatalf("seed %d starts with tetro %d, an O, S or Z", seed, first)
+		}
+	}
+}
+
+func TestRandomInRange(t *testing.T) {
+	r, err := NewRandomizer("random", 1, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tetro := range deal(r, 500) {
+		if tetro < 1 || tetro > 5 {
+			t.Fatalf("dealt tetro %d from a set of 5", tetro)
+		}
+	}
+}
+
+func TestUnknownRandomizer(t *testing.T) {
+	if _, err := NewRandomizer("8bag", 1, 7); err == nil {
+		t.Fatal("made a randomizer that doesn't exist")
+	}
+}
+
+func TestPeekPastTheBag(t *testing.T) {
+	g := NewGame(3, NewBagRandomizer(3, 1))
+	g.QueueLength = 10
+	g.SetNextTetroFromBag()
+	order := deal(NewBagRandomizer(3, 1), 30)
+
+	// the queue reaches into the second bag before the first has been dealt
+	if peeked := g.PeekNext(10); !reflect.DeepEqual(peeked, order[1:11]) {
+		t.Fatalf("peeked %v, want %v", peeked, order[1:11])
+	}
+	for i := 1; i < 20; i++ {
+		g.SetNextTetroFromBag()
+		if g.CurrentPiece.Tetro != order[i] || len(g.NextQueue) != 10 {
+			t.Fatalf("piece %d is %d with %d in the queue, want %d with 10", i, g.CurrentPiece.Tetro, len(g.NextQueue), order[i])
+		}
+		if peeked := g.PeekNext(10); !reflect.DeepEqual(peeked, order[i+1:i+11]) {
+			t.Fatalf("after piece %d peeked %v, want %v", i, peeked, order[i+1:i+11])
+		}
+	}
+
+	// peeking further than the queue goes only gives what's in it
+	g.QueueLength = 1
+	g.NextQueue = g.NextQueue[:1]
+	if peeked := g.PeekNext(5); len(peeked) != 1 {
+		t.Fatalf("peeked %d pieces with a queue of 1", len(peeked))
+	}
+}
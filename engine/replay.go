@@ -0,0 +1,254 @@
+package engine
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// the version of the replay format, this goes up whenever the format or the rules change
+// in a way that would make old replays play out differently
+const ReplayVersion = 8
+
+// ReplayHeader is everything needed to make the same game again before any inputs are played
+type ReplayHeader struct {
+	Version              int          `json:"version"`
+	Seed                 int64        `json:"seed"`
+	Randomizer           string       `json:"randomizer"`
+	Mode                 string       `json:"mode"`
+	QueueLength          int          `json:"queue_length"`
+	Gravity              GravityCurve `json:"gravity"`
+	StartLevel           int          `json:"start_level"`
+	LevelGoal            LevelGoal    `json:"level_goal"`
+	LockDelayMillis      int          `json:"lock_delay_millis"`
+	LockMode             LockMode     `json:"lock_mode"`
+	MaxLockResets        int          `json:"max_lock_resets"`
+	LineClearDelayMillis int          `json:"line_clear_delay_millis"`
+	Handling             Handling     `json:"handling"`
+	TopOut               TopOutRules  `json:"top_out"`
+	BoardWidth           int          `json:"board_width"`
+	BoardHeight          int          `json:"board_height"`
+	Pieces               *PieceSet    `json:"pieces"`
+	Finesse180           bool         `json:"finesse_180"`
+
+	// where a game that was carried on from a save was when recording started, the replay starts from here
+	// rather than from a new game made with the settings above, nil for a game recorded from its start
+	Start *SavedGame `json:"start,omitempty"`
+}
+
+// ReplayFrame is a single call to Game.Step, Time being how long the game had been running
+// when the step finished, so the dt of a frame is its Time minus the Time of the one before it
+type ReplayFrame struct {
+	Time  time.Duration
+	Input Input
+}
+
+// Replay is a recorded game, the header makes the game and the frames are fed through Game.Step
+type Replay struct {
+	Header ReplayHeader
+	Frames []ReplayFrame
+}
+
+// packs the input into one bit per key
+func (in Input) Bits() uint16 {
+	var bits uint16
+	for i, held := range in.fieldPointers() {
+		if *held {
+			bits |= 1 << i
+		}
+	}
+	return bits
+}
+
+// unpacks an input that was packed with Input.Bits
+func InputFromBits(bits uint16) Input {
+	var in Input
+	for i, held := range in.fieldPointers() {
+		*held = bits&(1<<i) != 0
+	}
+	return in
+}
+
+// the input fields in the order they are packed into bits when a replay is saved
+func (in *Input) fieldPointers() []*bool {
+	return []*bool{
+		&in.Left,
+		&in.Right,
+		&in.SoftDrop,
+		&in.HardDrop,
+		&in.RotateClockWise,
+		&in.RotateCounterClockWise,
+		&in.Rotate180,
+		&in.Hold,
+	}
+}
+
+// starts recording every step of the game from now on, this should be called before the first piece spawns,
+// or straight after a saved game is carried on, in which case the replay starts from where the game is
+func (g *Game) StartRecording() {
+	g.Recording = &Replay{
+		Header: ReplayHeader{
+			Version:              ReplayVersion,
+			Seed:                 g.Seed,
+			Randomizer:           g.Randomizer.Name(),
+			Mode:                 g.Mode.Name(),
+			QueueLength:          g.QueueLength,
+			Gravity:              g.Gravity,
+			StartLevel:           g.StartLevel,
+			LevelGoal:            g.LevelGoal,
+			LockDelayMillis:      g.LockDelayMillis,
+			LockMode:             g.LockMode,
+			MaxLockResets:        g.MaxLockResets,
+			LineClearDelayMillis: g.LineClearDelayMillis,
+			Handling:             g.Handling,
+			TopOut:               g.TopOut,
+			BoardWidth:           g.PlayingBoard.Width,
+			BoardHeight:          g.PlayingBoard.Visible,
+			Pieces:               g.Pieces,
+			Finesse180:           g.Finesse180,
+		},
+	}
+	if g.CurrentPiece != nil || g.PlayTime > 0 {
+		// a game that has already ended isn't stepped any more, so there's nothing to record
+		g.Recording.Header.Start, _ = g.Save()
+	}
+}
+
+// adds a step to the recording, if the game is being recorded
+func (g *Game) record(dt time.Duration, in Input) {
+	if g.Recording == nil {
+		return
+	}
+	g.recordTime += dt
+	g.Recording.Frames = append(g.Recording.Frames, ReplayFrame{Time: g.recordTime, Input: in})
+}
+
+// makes the game the replay was recorded from, ready for its first frame
+func (r *Replay) NewGame() (Game, error) {
+	if r.Header.Version != ReplayVersion {
+		return Game{}, fmt.Errorf("replay is version %d, this game can only play version %d", r.Header.Version, ReplayVersion)
+	}
+	if r.Header.Start != nil {
+		return r.Header.Start.Resume()
+	}
+	if r.Header.Pieces == nil {
+		return Game{}, fmt.Errorf("replay doesn't say which pieces it was played with")
+	}
+	randomizer, err := NewRandomizer(r.Header.Randomizer, r.Header.Seed, r.Header.Pieces.Len())
+	if err != nil {
+		return Game{}, err
+	}
+	mode, err := NewMode(r.Header.Mode)
+	if err != nil {
+		return Game{}, err
+	}
+	if err := CheckBoardSize(r.Header.BoardWidth, r.Header.BoardHeight, r.Header.Pieces); err != nil {
+		return Game{}, err
+	}
+	g := NewGame(r.Header.Seed, randomizer)
+	g.Pieces = r.Header.Pieces
+	g.SetBoardSize(r.Header.BoardWidth, r.Header.BoardHeight)
+	g.SetMode(mode)
+	g.QueueLength = r.Header.QueueLength
+	g.Gravity = r.Header.Gravity
+	g.LevelGoal = r.Header.LevelGoal
+	g.SetStartLevel(r.Header.StartLevel)
+	g.LockDelayMillis = r.Header.LockDelayMillis
+	g.LockMode = r.Header.LockMode
+	g.MaxLockResets = r.Header.MaxLockResets
+	g.LineClearDelayMillis = r.Header.LineClearDelayMillis
+	g.Handling = r.Header.Handling
+	g.TopOut = r.Header.TopOut
+	g.Finesse180 = r.Header.Finesse180
+	g.SetNextTetroFromBag()
+	return g, nil
+}
+
+// writes the replay as JSON lines, the header first and then one [time in nanoseconds, input bits] line per frame
+func (r *Replay) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	if err := enc.Encode(r.Header); err != nil {
+		return err
+	}
+	for _, f := range r.Frames {
+		if err := enc.Encode([2]int64{int64(f.Time), int64(f.Input.Bits())}); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// reads a replay that was written with Replay.Write
+func ReadReplay(r io.Reader) (*Replay, error) {
+	dec := json.NewDecoder(bufio.NewReader(r))
+	replay := &Replay{}
+	if err := dec.Decode(&replay.Header); err != nil {
+		return nil, fmt.Errorf("reading replay header: %w", err)
+	}
+	for dec.More() {
+		var frame [2]int64
+		if err := dec.Decode(&frame); err != nil {
+			return nil, fmt.Errorf("reading replay frame %d: %w", len(replay.Frames), err)
+		}
+		replay.Frames = append(replay.Frames, ReplayFrame{
+			Time:  time.Duration(frame[0]),
+			Input: InputFromBits(uint16(frame[1])),
+		})
+	}
+	return replay, nil
+}
+
+// ReplayPlayer feeds the frames of a replay through a game, as fast or as slow as it is told to
+type ReplayPlayer struct {
+	// the game being replayed
+	Game *Game
+
+	// how fast the replay plays, 1 is the speed it was recorded at
+	Speed float64
+
+	replay  *Replay
+	next    int
+	elapsed time.Duration
+}
+
+// makes a player for a replay, along with the game it plays through
+func NewReplayPlayer(r *Replay) (*ReplayPlayer, error) {
+	g, err := r.NewGame()
+	if err != nil {
+		return nil, err
+	}
+	return &ReplayPlayer{Game: &g, Speed: 1, replay: r}, nil
+}
+
+// moves the replay forward by dt of real time, playing every frame that falls in that time
+func (p *ReplayPlayer) Advance(dt time.Duration) {
+	p.elapsed += time.Duration(float64(dt) * p.Speed)
+	for !p.Done() && p.replay.Frames[p.next].Time <= p.elapsed {
+		p.StepFrame()
+	}
+}
+
+// plays exactly one frame of the replay
+func (p *ReplayPlayer) StepFrame() {
+	if p.Done() {
+		return
+	}
+	var last time.Duration
+	if p.next > 0 {
+		last = p.replay.Frames[p.next-1].Time
+	}
+	frame := p.replay.Frames[p.next]
+	p.Game.Step(frame.Time-last, frame.Input)
+	p.next++
+	if p.elapsed < frame.Time {
+		p.elapsed = frame.Time
+	}
+}
+
+// have all the frames been played
+func (p *ReplayPlayer) Done() bool {
+	return p.next >= len(p.replay.Frames)
+}
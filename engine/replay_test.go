@@ -0,0 +1,71 @@
+package engine
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// records a game of mashed keys with frames of uneven lengths like a real window, writes it, reads it back
+// and checks the replay plays out to the same board and score
+func TestReplayRoundTrip(t *testing.T) {
+	g := NewGame(5, NewBagRandomizer(5, 1))
+	g.Finesse180 = true
+	g.StartRecording()
+	g.SetNextTetroFromBag()
+	for i, in := range mashKeys(7, 1000) {
+		g.Step(time.Duration(8+i%17)*time.Millisecond, in)
+	}
+	if g.GameOver || g.PiecesPlaced < 10 {
+		t.Fatalf("the keys placed %d pieces with game over %v, want a game still going after at least 10", g.PiecesPlaced, g.GameOver)
+	}
+
+	var file bytes.Buffer
+	if err := g.Recording.Write(&file); err != nil {
+		t.Fatal(err)
+	}
+	replay, err := ReadReplay(&file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replay.Frames) != 1000 || !replay.Header.Finesse180 {
+		t.Fatalf("read back %d frames with finesse 180 %v, want 1000 with it on", len(replay.Frames), replay.Header.Finesse180)
+	}
+
+	player, err := NewReplayPlayer(replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	player.Advance(time.Hour)
+	if !player.Done() {
+		t.Fatal("the replay isn't done after an hour")
+	}
+	if player.Game.Score != g.Score || player.Game.PiecesPlaced != g.PiecesPlaced {
+		t.Fatalf("the replay scored %d from %d pieces, the game scored %d from %d", player.Game.Score, player.Game.PiecesPlaced, g.Score, g.PiecesPlaced)
+	}
+	if got, want := saveJSON(t, player.Game), saveJSON(t, &g); !bytes.Equal(got, want) {
+		t.Fatalf("the replay ended up at\n%s\nthe game was at\n%s", got, want)
+	}
+}
+
+func TestReplayUnknownVersion(t *testing.T) {
+	g := NewGame(5, NewBagRandomizer(5, 1))
+	g.StartRecording()
+	g.SetNextTetroFromBag()
+	g.Recording.Header.Version = ReplayVersion + 1
+	if _, err := NewReplayPlayer(g.Recording); err == nil {
+		t.Fatal("played a replay from a version that doesn't exist yet")
+	}
+}
+
+func TestReadReplayBadFrame(t *testing.T) {
+	var file bytes.Buffer
+	replay := &Replay{Header: ReplayHeader{Version: ReplayVersion}}
+	if err := replay.Write(&file); err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString("[1, 2, 3\n")
+	if _, err := ReadReplay(&file); err == nil {
+		t.Fatal("read a replay with a broken frame")
+	}
+}
@@ -0,0 +1,174 @@
+package engine
+
+// Rotation is which way a tetromino is facing, using the names from the Super Rotation System
+type Rotation int
+
+const (
+	// the way the tetro faces when it spawns
+	RotationSpawn Rotation = iota
+
+	// turned once clockwise from spawn
+	RotationRight
+
+	// turned twice from spawn
+	RotationTwo
+
+	// turned once counter clockwise from spawn
+	RotationLeft
+)
+
+// a kick is a change in which rotation a tetro is facing, from one state to another
+type kick struct {
+	From, To Rotation
+}
+
+// the wall kicks for J, L, S, T and Z, the offsets are tried in order until one fits,
+// these are written as Row, Col so a positive Row moves the tetro up
+var jlstzKicks = map[kick][]Point{
+	{RotationSpawn, RotationRight}: {{0, 0}, {0, -1}, {1, -1}, {-2, 0}, {-2, -1}},
+	{RotationRight, RotationSpawn}: {{0, 0}, {0, 1}, {-1, 1}, {2, 0}, {2, 1}},
+	{RotationRight, RotationTwo}:   {{0, 0}, {0, 1}, {-1, 1}, {2, 0}, {2, 1}},
+	{RotationTwo, RotationRight}:   {{0, 0}, {0, -1}, {1, -1}, {-2, 0}, {-2, -1}},
+	{RotationTwo, RotationLeft}:    {{0, 0}, {0, 1}, {1, 1}, {-2, 0}, {-2, 1}},
+	{RotationLeft, RotationTwo}:    {{0, 0}, {0, -1}, {-1, -1}, {2, 0}, {2, -1}},
+	{RotationLeft, RotationSpawn}:  {{0, 0}, {0, -1}, {-1, -1}, {2, 0}, {2, -1}},
+	{RotationSpawn, RotationLeft}:  {{0, 0}, {0, 1}, {1, 1}, {-2, 0}, {-2, 1}},
+}
+
+// the wall kicks for the I tetro
+var iKicks = map[kick][]Point{
+	{RotationSpawn, RotationRight}: {{0, 0}, {0, -2}, {0, 1}, {-1, -2}, {2, 1}},
+	{RotationRight, RotationSpawn}: {{0, 0}, {0, 2}, {0, -1}, {1, 2}, {-2, -1}},
+	{RotationRight, RotationTwo}:   {{0, 0}, {0, -1}, {0, 2}, {2, -1}, {-1, 2}},
+	{RotationTwo, RotationRight}:   {{0, 0}, {0, 1}, {0, -2}, {-2, 1}, {1, -2}},
+	{RotationTwo, RotationLeft}:    {{0, 0}, {0, 2}, {0, -1}, {1, 2}, {-2, -1}},
+	{RotationLeft, RotationTwo}:    {{0, 0}, {0, -2}, {0, 1}, {-1, -2}, {2, 1}},
+	{RotationLeft, RotationSpawn}:  {{0, 0}, {0, 1}, {0, -2}, {-2, 1}, {1, -2}},
+	{RotationSpawn, RotationLeft}:  {{0, 0}, {0, -1}, {0, 2}, {2, -1}, {-1, 2}},
+}
+
+// SRS has no kicks for turning twice, so these just try nudging the tetro up, sideways and down
+var halfTurnKicks = []Point{{0, 0}, {1, 0}, {0, 1}, {0, -1}, {-1, 0}}
+
+// a kickTable is the offsets to try for each quarter turn and for half turns, a piece with no kicks has neither
+type kickTable struct {
+	quarter map[kick][]Point
+	half    []Point
+}
+
+// the wall kicks pieces can use, by the name piece sets use for them,
+// the 2x tables are for pieces twice the usual size like the big set, kicking twice as far
+var kickTables = map[string]kickTable{
+	"srs":      {jlstzKicks, halfTurnKicks},
+	"srs-i":    {iKicks, halfTurnKicks},
+	"srs-2x":   {scaleKicks(jlstzKicks, 2), scalePoints(halfTurnKicks, 2)},
+	"srs-i-2x": {scaleKicks(iKicks, 2), scalePoints(halfTurnKicks, 2)},
+	"none":     {},
+}
+
+// the names of the wall kicks, for the error when a piece set uses one that doesn't exist
+var kickNames = []string{"srs", "srs-i", "srs-2x", "srs-i-2x", "none"}
+
+// returns a copy of a kick table with every offset multiplied by scale
+func scaleKicks(table map[kick][]Point, scale int) map[kick][]Point {
+	scaled := make(map[kick][]Point, len(table))
+	for k, offsets := range table {
+		scaled[k] = scalePoints(offsets, scale)
+	}
+	return scaled
+}
+
+// returns a copy of the points with both coordinates multiplied by scale
+func scalePoints(points []Point, scale int) []Point {
+	scaled := make([]Point, len(points))
+	for i, p := range points {
+		scaled[i] = Point{Row: p.Row * scale, Col: p.Col * scale}
+	}
+	return scaled
+}
+
+// gets the offsets to try when rotating a piece from one state to another with the named kicks
+func kicksFor(kicks string, from, to Rotation) []Point {
+	table := kickTables[kicks]
+	offsets := table.quarter[kick{from, to}]
+	if (from-to+4)%4 == 2 {
+		offsets = table.half
+	}
+	if offsets == nil {
+		// only turning in place
+		return []Point{{0, 0}}
+	}
+	return offsets
+}
+
+// rotates a point around a pivot in half pixels, turns is how many quarter turns clockwise
+func rotatePoint(p, pivot Point, turns int) Point {
+	dRow := 2*p.Row - pivot.Row
+	dCol := 2*p.Col - pivot.Col
+	for i := 0; i < (turns%4+4)%4; i++ {
+		dRow, dCol = -dCol, dRow
+	}
+	return Point{Row: (pivot.Row + dRow) / 2, Col: (pivot.Col + dCol) / 2}
+}
+
+// works out where the tetromino ends up after a number of quarter turns clockwise,
+// trying each wall kick in turn until fits says the kicked shape fits,
+// returns the rotated tetromino and which kick was used, or false if none of them fit
+func (t Tetromino) Rotated(turns int, fits func(Shape) bool) (Tetromino, int, bool) {
+	from := t.Rotation
+	to := (from + Rotation(turns) + 4) % 4
+
+	rotated := make(Shape, len(t.Shape))
+	for i, p := range t.Shape {
+		rotated[i] = rotatePoint(p, t.Pivot, turns)
+	}
+
+	kicked := make(Shape, len(rotated))
+	for k, offset := range kicksFor(t.Kicks, from, to) {
+		for i, p := range rotated {
+			kicked[i] = Point{Row: p.Row + offset.Row, Col: p.Col + offset.Col}
+		}
+		if !fits(kicked) {
+			continue
+		}
+		return Tetromino{
+			Shape:    kicked,
+			Tetro:    t.Tetro,
+			Rotation: to,
+			Kicks:    t.Kicks,
+			Pivot:    Point{Row: t.Pivot.Row + 2*offset.Row, Col: t.Pivot.Col + 2*offset.Col},
+		}, k, true
+	}
+	return t, 0, false
+}
+
+// rotates the falling piece by a number of quarter turns clockwise,
+// trying each wall kick in turn, returns false if none of them fit
+func (g *Game) rotate(turns int) bool {
+	rotated, k, ok := g.CurrentPiece.Rotated(turns, g.PlayingBoard.Fits)
+	if !ok {
+		return false
+	}
+
+	*g.CurrentPiece = rotated
+	g.LastMoveRotation = true
+	g.LastKick = k
+	g.LastHalfTurn = turns%4 == 2 || turns%4 == -2
+	g.pieceMoved()
+	return true
+}
+
+// rotates the falling piece clockwise, if it can
+func (g *Game) RotateClockWise() bool {
+	return g.rotate(1)
+}
+
+// rotates the falling piece counter clockwise, if it can
+func (g *Game) RotateCounterClockWise() bool {
+	return g.rotate(-1)
+}
+
+// rotates the falling piece twice, if it can
+func (g *Game) Rotate180() bool {
+	return g.rotate(2)
+}
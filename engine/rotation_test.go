@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"sort"
+	"testing"
+)
+
+// the pixels of a shape sorted, so shapes can be compared whatever order their points are in
+func sortedShape(s Shape) Shape {
+	sorted := append(Shape(nil), s...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Row != sorted[j].Row {
+			return sorted[i].Row < sorted[j].Row
+		}
+		return sorted[i].Col < sorted[j].Col
+	})
+	return sorted
+}
+
+func sameShape(a, b Shape) bool {
+	a, b = sortedShape(a), sortedShape(b)
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestKicks(t *testing.T) {
+	var (
+		L = Tetro(2)
+		J = Tetro(3)
+		I = Tetro(4)
+		T = Tetro(5)
+		S = Tetro(6)
+		Z = Tetro(7)
+	)
+	tests := []struct {
+		name     string
+		tetro    Tetro
+		keys     string
+		turns    int
+		rotation Rotation
+		kick     int
+		want     Shape
+	}{
+		{"T 0 to R in the open", T, "", 1, RotationRight, 0, Shape{{22, 6}, {23, 5}, {22, 5}, {21, 5}}},
+		{"T R to 0 against the left wall", T, "CW", -1, RotationSpawn, 1, Shape{{23, 1}, {22, 0}, {22, 1}, {22, 2}}},
+		{"J L to 0 against the right wall", J, "AE", 1, RotationSpawn, 1, Shape{{23, 7}, {22, 7}, {22, 8}, {22, 9}}},
+		{"S 0 to R on the floor", S, "D", 1, RotationRight, 2, Shape{{1, 5}, {0, 5}, {2, 4}, {1, 4}}},
+		{"Z 0 to L on the floor", Z, "D", -1, RotationLeft, 2, Shape{{0, 5}, {1, 5}, {1, 6}, {2, 6}}},
+		{"T 0 to 2 on the floor", T, "D", 2, RotationTwo, 1, Shape{{0, 5}, {1, 6}, {1, 5}, {1, 4}}},
+		{"L 2 to 0 on the floor", L, "FD", 2, RotationSpawn, 0, Shape{{2, 6}, {1, 4}, {1, 5}, {1, 6}}},
+		{"I 0 to R on the floor", I, "D", 1, RotationRight, 4, Shape{{3, 7}, {2, 7}, {1, 7}, {0, 7}}},
+		{"I 0 to R against the right wall and the top", I, "E", 1, RotationRight, 3, Shape{{23, 6}, {22, 6}, {21, 6}, {20, 6}}},
+		{"I L to 0 against the left wall", I, "AW", 1, RotationSpawn, 1, Shape{{22, 0}, {22, 1}, {22, 2}, {22, 3}}},
+		{"I R to 0 against the right wall", I, "CE", -1, RotationSpawn, 2, Shape{{22, 6}, {22, 7}, {22, 8}, {22, 9}}},
+	}
+	for _, test := range tests {
+		g := NewGame(1, NewBagRandomizer(1, 1))
+		piece := pieceAfter(&g, test.tetro, test.keys)
+		rotated, kick, ok := piece.Rotated(test.turns, g.PlayingBoard.Fits)
+		if !ok {
+			t.Errorf("%s: didn't rotate", test.name)
+			continue
+		}
+		if rotated.Rotation != test.rotation || kick != test.kick || !sameShape(rotated.Shape, test.want) {
+			t.Errorf("%s: facing %d with kick %d at %v, want facing %d with kick %d at %v",
+				test.name, rotated.Rotation, kick, rotated.Shape, test.rotation, test.kick, test.want)
+		}
+	}
+}
+
+func TestRotateBlocked(t *testing.T) {
+	// an I lying on the floor under four full rows can't stand up anywhere
+	g := NewGame(1, NewBagRandomizer(1, 1))
+	piece := pieceAfter(&g, Tetro(4), "D")
+	g.CurrentPiece = &piece
+	for i := 1; i <= 4; i++ {
+		for j := 0; j < g.PlayingBoard.Width; j++ {
+			g.PlayingBoard.Set(Point{Row: i, Col: j}, GarbagePixel)
+		}
+	}
+	before := append(Shape(nil), piece.Shape...)
+	if g.RotateClockWise() || g.RotateCounterClockWise() {
+		t.Fatalf("the I turned to %v", g.CurrentPiece.Shape)
+	}
+	if !sameShape(g.CurrentPiece.Shape, before) || g.CurrentPiece.Rotation != RotationSpawn {
+		t.Fatalf("a rotation that didn't fit moved the I to %v", g.CurrentPiece.Shape)
+	}
+}
+
+func TestORotation(t *testing.T) {
+	g := NewGame(1, NewBagRandomizer(1, 1))
+	for _, turns := range []int{1, -1, 2} {
+		start := *g.newPiece(Tetro(1))
+		rotated, kick, ok := start.Rotated(turns, g.PlayingBoard.Fits)
+		if !ok || kick != 0 || !sameShape(rotated.Shape, start.Shape) || rotated.Pivot != start.Pivot {
+			t.Errorf("turning the O %d times moved it from %v to %v with kick %d", turns, start.Shape, rotated.Shape, kick)
+		}
+	}
+}
+
+func TestFourTurns(t *testing.T) {
+	g := NewGame(1, NewBagRandomizer(1, 1))
+	for tetro := Tetro(1); tetro <= 7; tetro++ {
+		start := pieceAfter(&g, tetro, "D")
+		piece := start
+		for i := 0; i < 4; i++ {
+			piece, _, _ = piece.Rotated(1, func(Shape) bool { return true })
+		}
+		if piece.Rotation != RotationSpawn || !sameShape(piece.Shape, start.Shape) {
+			t.Errorf("turning %d four times moved it from %v to %v", tetro, start.Shape, piece.Shape)
+		}
+	}
+}
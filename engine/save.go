@@ -0,0 +1,294 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// the version of the save format, this goes up whenever the format changes so an old save is never loaded wrong
+const SaveVersion = 1
+
+// SavedGame is a game in the middle of being played, written down so it can be carried on later from the same step,
+// the settings it was started with along with everything that has happened since
+type SavedGame struct {
+	Version int `json:"version"`
+
+	// the settings the game was started with
+	Seed                 int64        `json:"seed"`
+	Mode                 string       `json:"mode"`
+	QueueLength          int          `json:"queue_length"`
+	Gravity              GravityCurve `json:"gravity"`
+	StartLevel           int          `json:"start_level"`
+	LevelGoal            LevelGoal    `json:"level_goal"`
+	LockDelayMillis      int          `json:"lock_delay_millis"`
+	LockMode             LockMode     `json:"lock_mode"`
+	MaxLockResets        int          `json:"max_lock_resets"`
+	LineClearDelayMillis int          `json:"line_clear_delay_millis"`
+	Handling             Handling     `json:"handling"`
+	TopOut               TopOutRules  `json:"top_out"`
+	Finesse180           bool         `json:"finesse_180"`
+	Pieces               *PieceSet    `json:"pieces"`
+
+	// the board, one row of pixels from the bottom up, along with how wide it is and how many rows can be seen
+	BoardWidth  int       `json:"board_width"`
+	BoardHeight int       `json:"board_height"`
+	Board       [][]Pixel `json:"board"`
+
+	// where the randomizer is up to and the mode's progress towards its goal, like the splits of a sprint
+	Randomizer RandomizerState `json:"randomizer"`
+	ModeState  json.RawMessage `json:"mode_state"`
+
+	// the pieces, nil for the current piece while waiting for the next one to spawn after a line clear
+	CurrentPiece *Tetromino `json:"current_piece"`
+	HeldPiece    int        `json:"held_piece"`
+	CanHold      bool       `json:"can_hold"`
+	NextQueue    []Tetro    `json:"next_queue"`
+
+	// the score and how far through the game it is
+	Score        int           `json:"score"`
+	LinesCleared int           `json:"lines_cleared"`
+	Level        int           `json:"level"`
+	GoalLines    int           `json:"goal_lines"`
+	PlayTime     time.Duration `json:"play_time"`
+	Combo        int           `json:"combo"`
+	BackToBack   bool          `json:"back_to_back"`
+	PiecesPlaced int           `json:"pieces_placed"`
+
+	// what the current piece has done, for t-spins, finesse and the lock delay
+	LastMoveRotation  bool `json:"last_move_rotation"`
+	LastKick          int  `json:"last_kick"`
+	LastHalfTurn      bool `json:"last_half_turn"`
+	PieceKeys         int  `json:"piece_keys"`
+	PieceSoftDropped  bool `json:"piece_soft_dropped"`
+	FinesseFaults     int  `json:"finesse_faults"`
+	LastFinesseFaults int  `json:"last_finesse_faults"`
+	FinessePieces     int  `json:"finesse_pieces"`
+	FinessePerfect    int  `json:"finesse_perfect"`
+
+	IncomingGarbage []Garbage `json:"incoming_garbage"`
+
+	// the timers, so the piece falls, locks and shifts on the same steps as it would have
+	FallProgress   float64       `json:"fall_progress"`
+	LockTimer      time.Duration `json:"lock_timer"`
+	LockResets     int           `json:"lock_resets"`
+	LowestRow      int           `json:"lowest_row"`
+	ShiftDirection int           `json:"shift_direction"`
+	DASTimer       time.Duration `json:"das_timer"`
+	ARRTimer       time.Duration `json:"arr_timer"`
+	SpawnTimer     time.Duration `json:"spawn_timer"`
+	LastInput      Input         `json:"last_input"`
+}
+
+// writes the game down so it can be carried on later, a game that has already ended can't be saved
+func (g *Game) Save() (*SavedGame, error) {
+	if g.GameOver || g.Finished {
+		return nil, errors.New("the game has already ended")
+	}
+	modeState, err := json.Marshal(g.Mode)
+	if err != nil {
+		return nil, fmt.Errorf("saving the mode: %w", err)
+	}
+	board := make([][]Pixel, g.PlayingBoard.Height)
+	for i, row := range g.PlayingBoard.Pixels {
+		board[i] = append([]Pixel(nil), row...)
+	}
+	var current *Tetromino
+	if g.CurrentPiece != nil {
+		piece := g.CurrentPiece.shifted(0)
+		current = &piece
+	}
+	return &SavedGame{
+		Version:              SaveVersion,
+		Seed:                 g.Seed,
+		Mode:                 g.Mode.Name(),
+		QueueLength:          g.QueueLength,
+		Gravity:              g.Gravity,
+		StartLevel:           g.StartLevel,
+		LevelGoal:            g.LevelGoal,
+		LockDelayMillis:      g.LockDelayMillis,
+		LockMode:             g.LockMode,
+		MaxLockResets:        g.MaxLockResets,
+		LineClearDelayMillis: g.LineClearDelayMillis,
+		Handling:             g.Handling,
+		TopOut:               g.TopOut,
+		Finesse180:           g.Finesse180,
+		Pieces:               g.Pieces,
+		BoardWidth:           g.PlayingBoard.Width,
+		BoardHeight:          g.PlayingBoard.Visible,
+		Board:                board,
+		Randomizer:           g.Randomizer.State(),
+		ModeState:            modeState,
+		CurrentPiece:         current,
+		HeldPiece:            g.HeldPiece,
+		CanHold:              g.CanHold,
+		NextQueue:            append([]Tetro(nil), g.NextQueue...),
+		Score:                g.Score,
+		LinesCleared:         g.LinesCleared,
+		Level:                g.Level,
+		GoalLines:            g.GoalLines,
+		PlayTime:             g.PlayTime,
+		Combo:                g.Combo,
+		BackToBack:           g.BackToBack,
+		PiecesPlaced:         g.PiecesPlaced,
+		LastMoveRotation:     g.LastMoveRotation,
+		LastKick:             g.LastKick,
+		LastHalfTurn:         g.LastHalfTurn,
+		PieceKeys:            g.PieceKeys,
+		PieceSoftDropped:     g.PieceSoftDropped,
+		FinesseFaults:        g.FinesseFaults,
+		LastFinesseFaults:    g.LastFinesseFaults,
+		FinessePieces:        g.FinessePieces,
+		FinessePerfect:       g.FinessePerfect,
+		IncomingGarbage:      append([]Garbage(nil), g.IncomingGarbage...),
+		FallProgress:         g.FallProgress,
+		LockTimer:            g.LockTimer,
+		LockResets:           g.LockResets,
+		LowestRow:            g.LowestRow,
+		ShiftDirection:       g.ShiftDirection,
+		DASTimer:             g.DASTimer,
+		ARRTimer:             g.ARRTimer,
+		SpawnTimer:           g.SpawnTimer,
+		LastInput:            g.LastInput,
+	}, nil
+}
+
+// makes the game the save was taken from again, it carries on exactly where it was saved,
+// the board is set up by the save rather than by the mode, so a dig doesn't get its garbage twice
+func (s *SavedGame) Resume() (Game, error) {
+	if s.Version != SaveVersion {
+		return Game{}, fmt.Errorf("save is version %d, this game can only load version %d", s.Version, SaveVersion)
+	}
+	if s.Pieces == nil {
+		return Game{}, errors.New("save doesn't say which pieces the game is played with")
+	}
+	if err := CheckBoardSize(s.BoardWidth, s.BoardHeight, s.Pieces); err != nil {
+		return Game{}, err
+	}
+	if s.Randomizer.Pieces != s.Pieces.Len() {
+		return Game{}, fmt.Errorf("the randomizer picks from %d kinds of piece, the piece set has %d", s.Randomizer.Pieces, s.Pieces.Len())
+	}
+	randomizer, err := RestoreRandomizer(s.Seed, s.Randomizer)
+	if err != nil {
+		return Game{}, err
+	}
+	mode, err := NewMode(s.Mode)
+	if err != nil {
+		return Game{}, err
+	}
+	// a marathon has nothing to carry on, the other modes are pointers the progress can be read into
+	if _, ok := mode.(Marathon); !ok {
+		if err := json.Unmarshal(s.ModeState, mode); err != nil {
+			return Game{}, fmt.Errorf("reading the %s progress: %w", s.Mode, err)
+		}
+	}
+
+	g := NewGame(s.Seed, randomizer)
+	g.Pieces = s.Pieces
+	g.SetBoardSize(s.BoardWidth, s.BoardHeight)
+	if len(s.Board) != g.PlayingBoard.Height {
+		return Game{}, fmt.Errorf("the board has %d rows, expected %d", len(s.Board), g.PlayingBoard.Height)
+	}
+	for i, row := range s.Board {
+		if len(row) != g.PlayingBoard.Width {
+			return Game{}, fmt.Errorf("row %d of the board is %d wide, expected %d", i, len(row), g.PlayingBoard.Width)
+		}
+		for j, pixel := range row {
+			if pixel != GarbagePixel && (pixel < 0 || int(pixel) > s.Pieces.Len()) {
+				return Game{}, fmt.Errorf("the board has pixel %d in it at %v", pixel, Point{i, j})
+			}
+			g.PlayingBoard.Set(Point{i, j}, pixel)
+		}
+	}
+
+	if err := s.checkTetros(); err != nil {
+		return Game{}, err
+	}
+	if s.CurrentPiece != nil {
+		piece := s.CurrentPiece.shifted(0)
+		if _, ok := kickTables[piece.Kicks]; !ok {
+			return Game{}, fmt.Errorf("the current piece has unknown kicks %q", piece.Kicks)
+		}
+		if g.Collides(piece.Shape) {
+			return Game{}, errors.New("the current piece is inside the stack")
+		}
+		g.CurrentPiece = &piece
+	}
+
+	g.Mode = mode
+	g.QueueLength = s.QueueLength
+	g.Gravity = s.Gravity
+	g.StartLevel = s.StartLevel
+	g.LevelGoal = s.LevelGoal
+	g.LockDelayMillis = s.LockDelayMillis
+	g.LockMode = s.LockMode
+	g.MaxLockResets = s.MaxLockResets
+	g.LineClearDelayMillis = s.LineClearDelayMillis
+	g.Handling = s.Handling
+	g.TopOut = s.TopOut
+	g.Finesse180 = s.Finesse180
+	g.HeldPiece = s.HeldPiece
+	g.CanHold = s.CanHold
+	g.NextQueue = append([]Tetro(nil), s.NextQueue...)
+	g.Score = s.Score
+	g.LinesCleared = s.LinesCleared
+	g.Level = s.Level
+	g.GoalLines = s.GoalLines
+	g.PlayTime = s.PlayTime
+	g.Combo = s.Combo
+	g.BackToBack = s.BackToBack
+	g.PiecesPlaced = s.PiecesPlaced
+	g.LastMoveRotation = s.LastMoveRotation
+	g.LastKick = s.LastKick
+	g.LastHalfTurn = s.LastHalfTurn
+	g.PieceKeys = s.PieceKeys
+	g.PieceSoftDropped = s.PieceSoftDropped
+	g.FinesseFaults = s.FinesseFaults
+	g.LastFinesseFaults = s.LastFinesseFaults
+	g.FinessePieces = s.FinessePieces
+	g.FinessePerfect = s.FinessePerfect
+	g.IncomingGarbage = append([]Garbage(nil), s.IncomingGarbage...)
+	g.FallProgress = s.FallProgress
+	g.LockTimer = s.LockTimer
+	g.LockResets = s.LockResets
+	g.LowestRow = s.LowestRow
+	g.ShiftDirection = s.ShiftDirection
+	g.DASTimer = s.DASTimer
+	g.ARRTimer = s.ARRTimer
+	g.SpawnTimer = s.SpawnTimer
+	g.LastInput = s.LastInput
+	return g, nil
+}
+
+// checks every tetro in the save is one of the piece set
+func (s *SavedGame) checkTetros() error {
+	tetros := append([]Tetro{Tetro(s.HeldPiece)}, s.NextQueue...)
+	if s.CurrentPiece != nil {
+		tetros = append(tetros, s.CurrentPiece.Tetro)
+	}
+	for i, t := range tetros {
+		// only the held piece can be empty
+		if (t < 1 && !(i == 0 && t == 0)) || int(t) > s.Pieces.Len() {
+			return fmt.Errorf("the save has tetro %d in it, there are only %d", t, s.Pieces.Len())
+		}
+	}
+	return nil
+}
+
+// writes the save as JSON
+func (s *SavedGame) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// reads a save that was written with SavedGame.Write
+func ReadSavedGame(r io.Reader) (*SavedGame, error) {
+	s := &SavedGame{}
+	if err := json.NewDecoder(r).Decode(s); err != nil {
+		return nil, fmt.Errorf("reading save: %w", err)
+	}
+	return s, nil
+}
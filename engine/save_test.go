@@ -0,0 +1,272 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// the same keys played at random every time for the same seed, for each piece it is turned, held now and then,
+// shifted left or right and then dropped, or soft dropped so it locks by itself
+func mashKeys(seed int64, steps int) []Input {
+	rng := rand.New(rand.NewSource(seed))
+	var inputs []Input
+	for len(inputs) < steps {
+		var turn Input
+		switch rng.Intn(4) {
+		case 1:
+			turn.RotateClockWise = true
+		case 2:
+			turn.RotateCounterClockWise = true
+		case 3:
+			turn.Rotate180 = true
+		}
+		turn.Hold = rng.Intn(8) == 0
+		inputs = append(inputs, turn, Input{})
+
+		// tapping so each tap moves it one column, or holding long enough for DAS to take it to the wall
+		shift := Input{Left: rng.Intn(2) == 0}
+		shift.Right = !shift.Left
+		if rng.Intn(4) == 0 {
+			for k := 0; k < 30; k++ {
+				inputs = append(inputs, shift)
+			}
+		} else {
+			for k := rng.Intn(5); k > 0; k-- {
+				inputs = append(inputs, shift, Input{})
+			}
+		}
+		inputs = append(inputs, Input{})
+
+		if rng.Intn(4) == 0 {
+			for k := 0; k < 60; k++ {
+				inputs = append(inputs, Input{SoftDrop: true})
+			}
+		} else {
+			inputs = append(inputs, Input{HardDrop: true})
+		}
+		inputs = append(inputs, Input{})
+	}
+	return inputs[:steps]
+}
+
+// writes the game down as JSON, for checking two games are in the same state
+func saveJSON(t *testing.T, g *Game) []byte {
+	t.Helper()
+	s, err := g.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// saves the game through a file and loads it again
+func roundTrip(t *testing.T, g *Game) Game {
+	t.Helper()
+	s, err := g.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file bytes.Buffer
+	if err := s.Write(&file); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := ReadSavedGame(&file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resumed, err := loaded.Resume()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resumed
+}
+
+func TestSaveRoundTrip(t *testing.T) {
+	const dt = 16 * time.Millisecond
+	games := []struct {
+		randomizer, mode, pieces string
+		width, height            int
+	}{
+		{"7bag", "marathon", "tetrominoes", 10, 20},
+		{"14bag", "sprint", "tetrominoes", 10, 20},
+		{"random", "ultra", "small", 6, 12},
+		{"history", "dig", "tetrominoes", 10, 30},
+		{"7bag", "marathon", "pentominoes", 12, 24},
+		// narrow boards so rows fill up and clear often
+		{"7bag", "marathon", "small", 4, 16},
+		{"random", "sprint", "tetrominoes", 4, 20},
+	}
+	for _, game := range games {
+		pieces, err := LoadPieceSet(game.pieces)
+		if err != nil {
+			t.Fatal(err)
+		}
+		randomizer, err := NewRandomizer(game.randomizer, 3, pieces.Len())
+		if err != nil {
+			t.Fatal(err)
+		}
+		mode, err := NewMode(game.mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g := NewGame(3, randomizer)
+		g.Pieces = pieces
+		g.SetBoardSize(game.width, game.height)
+		g.SetMode(mode)
+		// a line clear delay so some saves are taken while waiting for the next piece
+		g.LineClearDelayMillis = 300
+		g.SetNextTetroFromBag()
+
+		inputs := mashKeys(5, 4000)
+		for i, in := range inputs {
+			if g.GameOver || g.Finished {
+				break
+			}
+			// every so often the game is saved and carried on from the save, which has to play exactly the same
+			if i%13 == 0 {
+				before := saveJSON(t, &g)
+				resumed := roundTrip(t, &g)
+				if after := saveJSON(t, &resumed); !bytes.Equal(before, after) {
+					t.Fatalf("%s %s: step %d: the resumed game is\n%s\nexpected\n%s", game.randomizer, game.mode, i, after, before)
+				}
+				g = resumed
+			}
+			g.Step(dt, in)
+		}
+		if g.PiecesPlaced < 10 {
+			t.Fatalf("%s %s: only %d pieces were placed, the keys aren't playing the game", game.randomizer, game.mode, g.PiecesPlaced)
+		}
+	}
+}
+
+// plays two games with the same keys, one of them saved and loaded part of the way through, and checks they end the same
+func TestResumedGamePlaysIdentically(t *testing.T) {
+	const dt = 16 * time.Millisecond
+	inputs := mashKeys(9, 6000)
+
+	played := NewGame(11, NewBagRandomizer(11, 1))
+	played.SetNextTetroFromBag()
+	steps := 0
+	for _, in := range inputs {
+		if played.GameOver {
+			break
+		}
+		played.Step(dt, in)
+		steps++
+	}
+
+	// saving half way through the game
+	resumed := NewGame(11, NewBagRandomizer(11, 1))
+	resumed.SetNextTetroFromBag()
+	for i, in := range inputs[:steps] {
+		if i == steps/2 {
+			resumed = roundTrip(t, &resumed)
+		}
+		resumed.Step(dt, in)
+	}
+	if played.GameOver != resumed.GameOver || played.GameOverReason != resumed.GameOverReason {
+		t.Fatalf("one game ended with %q and the other with %q", played.GameOverReason, resumed.GameOverReason)
+	}
+	if played.Score != resumed.Score || played.LinesCleared != resumed.LinesCleared || played.PiecesPlaced != resumed.PiecesPlaced {
+		t.Fatalf("the resumed game scored %d with %d lines and %d pieces, the game played straight through scored %d with %d lines and %d pieces",
+			resumed.Score, resumed.LinesCleared, resumed.PiecesPlaced, played.Score, played.LinesCleared, played.PiecesPlaced)
+	}
+	for i := range played.PlayingBoard.Pixels {
+		for j := range played.PlayingBoard.Pixels[i] {
+			if played.PlayingBoard.Pixels[i][j] != resumed.PlayingBoard.Pixels[i][j] {
+				t.Fatalf("the boards are different at %v", Point{i, j})
+			}
+		}
+	}
+	if played.PiecesPlaced < 10 {
+		t.Fatalf("only %d pieces were placed, the keys aren't playing the game", played.PiecesPlaced)
+	}
+}
+
+// a history randomizer for another piece set saved before its history has filled up carries on dealing the same pieces
+func TestSaveHistoryOfAnotherSet(t *testing.T) {
+	pieces, err := LoadPieceSet("pentominoes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	randomizer, err := NewRandomizer("history", 3, pieces.Len())
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGame(3, randomizer)
+	g.Pieces = pieces
+	g.SetBoardSize(12, 24)
+	g.QueueLength = 1
+	g.SetNextTetroFromBag()
+
+	resumed := roundTrip(t, &g)
+	for i := 0; i < 50; i++ {
+		if want, got := g.Randomizer.Next(), resumed.Randomizer.Next(); got != want {
+			t.Fatalf("tetro %d after carrying on is %d, want %d", i, got, want)
+		}
+	}
+}
+
+func TestSaveVersion(t *testing.T) {
+	g := NewGame(1, NewBagRandomizer(1, 1))
+	g.SetNextTetroFromBag()
+	s, err := g.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Version = SaveVersion + 1
+	if _, err := s.Resume(); err == nil {
+		t.Fatal("a save from a newer version was loaded")
+	}
+
+	g.GameOver = true
+	if _, err := g.Save(); err == nil {
+		t.Fatal("a game that was over was saved")
+	}
+}
+
+// records a game carried on from a save, and checks the replay plays out to the same place as the game did
+func TestRecordingResumedGame(t *testing.T) {
+	const dt = 16 * time.Millisecond
+	inputs := mashKeys(9, 300)
+
+	g := NewGame(11, NewBagRandomizer(11, 1))
+	g.SetNextTetroFromBag()
+	for _, in := range inputs[:100] {
+		g.Step(dt, in)
+	}
+	g = roundTrip(t, &g)
+	g.StartRecording()
+	for _, in := range inputs[100:] {
+		g.Step(dt, in)
+	}
+	if g.GameOver {
+		t.Fatal("the game ended before the replay could be checked")
+	}
+
+	var file bytes.Buffer
+	if err := g.Recording.Write(&file); err != nil {
+		t.Fatal(err)
+	}
+	replay, err := ReadReplay(&file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	player, err := NewReplayPlayer(replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for !player.Done() {
+		player.StepFrame()
+	}
+	if got, want := saveJSON(t, player.Game), saveJSON(t, &g); !bytes.Equal(got, want) {
+		t.Fatalf("the replay ended up at\n%s\nthe game was at\n%s", got, want)
+	}
+}
@@ -0,0 +1,212 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TSpin is what kind of t-spin a locked piece was, if it was one at all
+type TSpin int
+
+const (
+	// not a t-spin
+	TSpinNone TSpin = iota
+
+	// a t-spin where only one of the corners in front of the T is filled
+	TSpinMini
+
+	// a t-spin where both corners in front of the T are filled
+	TSpinFull
+)
+
+// ClearEvent describes what happened when a piece locked, what lines it cleared and what it scored,
+// one is made for every piece that locks even if it didn't clear anything
+type ClearEvent struct {
+	// the tetro that locked
+	Tetro Tetro
+
+	// how many lines were cleared
+	Lines int
+
+	// was the piece a t-spin
+	TSpin TSpin
+
+	// did this clear get the back-to-back bonus
+	BackToBack bool
+
+	// how many pieces in a row have cleared lines before this one, 0 for the first
+	Combo int
+
+	// is the board empty after the clear
+	PerfectClear bool
+
+	// how many points this clear was worth, with the level already multiplied in
+	Points int
+
+	// the level the points were scored at
+	Level int
+
+	// how many keys were pressed to move and rotate the piece
+	Keys int
+
+	// how many more keys were pressed than were needed to place the piece, 0 if it wasn't judged on finesse
+	FinesseFaults int
+}
+
+// the points for clearing lines, indexed by the number of lines cleared,
+// clearing more lines than a table goes up to, like 5 with a pentomino, scores the last entry
+var linePoints = map[TSpin][]int{
+	TSpinNone: {0, 100, 300, 500, 800},
+	TSpinMini: {100, 200, 400},
+	TSpinFull: {400, 800, 1200, 1600},
+}
+
+// the points for clearing the whole board, indexed by the number of lines cleared, capped the same way
+var perfectClearPoints = []int{0, 800, 1200, 1800, 2000}
+
+// the points for a back-to-back tetris perfect clear, instead of the 2000 for a normal one
+const backToBackTetrisPerfectClearPoints = 3200
+
+// the points for every pixel the piece is soft or hard dropped
+const (
+	SoftDropPoints = 1
+	HardDropPoints = 2
+)
+
+// adds a function to call with the ClearEvent every time a piece locks
+func (g *Game) OnClear(f func(ClearEvent)) {
+	g.clearListeners = append(g.clearListeners, f)
+}
+
+// checks if a pixel counts as filled for the t-spin corners, the walls and floor count as filled
+func (g *Game) filledForCorner(p Point) bool {
+	if p.Row < 0 || p.Col < 0 || p.Col >= g.PlayingBoard.Width {
+		return true
+	}
+	return g.PlayingBoard.Get(p) != Pixel(0)
+}
+
+// works out if the current piece is a t-spin using the 3 corner rule,
+// this has to be called after the piece has landed but before lines are cleared
+func (g *Game) detectTSpin() TSpin {
+	piece := g.CurrentPiece
+	if !g.Pieces.Piece(piece.Tetro).Spins || !g.LastMoveRotation {
+		return TSpinNone
+	}
+
+	center := Point{Row: piece.Pivot.Row / 2, Col: piece.Pivot.Col / 2}
+	topLeft := g.filledForCorner(Point{center.Row + 1, center.Col - 1})
+	topRight := g.filledForCorner(Point{center.Row + 1, center.Col + 1})
+	bottomLeft := g.filledForCorner(Point{center.Row - 1, center.Col - 1})
+	bottomRight := g.filledForCorner(Point{center.Row - 1, center.Col + 1})
+
+	filled := 0
+	for _, corner := range []bool{topLeft, topRight, bottomLeft, bottomRight} {
+		if corner {
+			filled++
+		}
+	}
+	if filled < 3 {
+		return TSpinNone
+	}
+
+	// the two corners on the side the T is pointing
+	var front1, front2 bool
+	switch piece.Rotation {
+	case RotationSpawn:
+		front1, front2 = topLeft, topRight
+	case RotationRight:
+		front1, front2 = topRight, bottomRight
+	case RotationTwo:
+		front1, front2 = bottomLeft, bottomRight
+	case RotationLeft:
+		front1, front2 = topLeft, bottomLeft
+	}
+
+	// the last kick of a quarter turn moves the T far enough that it always counts as a full t-spin
+	if front1 && front2 || g.LastKick == 4 && !g.LastHalfTurn {
+		return TSpinFull
+	}
+	return TSpinMini
+}
+
+// works out the points for a piece that just locked, updating the combo and back-to-back,
+// then tells everyone listening about it
+func (g *Game) scoreClear(tetro Tetro, lines int, tspin TSpin, level int) ClearEvent {
+	event := ClearEvent{
+		Tetro:         tetro,
+		Lines:         lines,
+		TSpin:         tspin,
+		Level:         level,
+		Keys:          g.PieceKeys,
+		FinesseFaults: g.LastFinesseFaults,
+	}
+
+	event.Points = capped(linePoints[tspin], lines)
+
+	if lines > 0 {
+		// tetrises (or more, with bigger pieces) and t-spins that clear lines are difficult,
+		// two in a row gets half as many points again
+		difficult := lines >= 4 || tspin != TSpinNone
+		if difficult && g.BackToBack {
+			event.BackToBack = true
+			event.Points += event.Points / 2
+		}
+		g.BackToBack = difficult
+
+		g.Combo++
+		event.Combo = g.Combo
+
+		// checking if every pixel of the board is empty
+		event.PerfectClear = g.PlayingBoard.Empty()
+	} else {
+		g.Combo = -1
+	}
+
+	event.Points += 50 * event.Combo
+	if event.PerfectClear {
+		if lines >= 4 && event.BackToBack {
+			event.Points += backToBackTetrisPerfectClearPoints
+		} else {
+			event.Points += capped(perfectClearPoints, lines)
+		}
+	}
+	event.Points *= level
+
+	g.Score += event.Points
+	for _, f := range g.clearListeners {
+		f(event)
+	}
+	return event
+}
+
+// the text to show for this clear, like "T-SPIN DOUBLE", "B2B" and "3 COMBO", or "5 LINES" for more than a tetris,
+// one line each, this is empty if the clear was nothing special
+func (e ClearEvent) Labels() []string {
+	var labels []string
+
+	names := []string{"", "SINGLE", "DOUBLE", "TRIPLE", "TETRIS"}
+	name := fmt.Sprintf("%d LINES", e.Lines)
+	if e.Lines < len(names) {
+		name = names[e.Lines]
+	}
+	switch e.TSpin {
+	case TSpinMini:
+		name = strings.TrimSpace("T-SPIN MINI " + name)
+	case TSpinFull:
+		name = strings.TrimSpace("T-SPIN " + name)
+	}
+	if name != "" {
+		labels = append(labels, name)
+	}
+	if e.BackToBack {
+		labels = append(labels, "B2B")
+	}
+	if e.Combo > 0 {
+		labels = append(labels, fmt.Sprintf("%d COMBO", e.Combo))
+	}
+	if e.PerfectClear {
+		labels = append(labels, "PERFECT CLEAR")
+	}
+	return labels
+}
@@ -0,0 +1,319 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// a piece to place and the keys that take it from where it spawns to where it locks,
+// L and R move it, C, A and F turn it clockwise, anticlockwise and round, D drops it as far as it goes
+type placement struct {
+	tetro Tetro
+	keys  string
+}
+
+// makes a game with rows of the board filled in from the bottom up, X for a pixel of garbage
+func newBoardGame(rows []string) Game {
+	g := NewGame(1, NewBagRandomizer(1, 1))
+	g.SetNextTetroFromBag()
+	for i, row := range rows {
+		for j, c := range row {
+			if c == 'X' {
+				g.PlayingBoard.Set(Point{i, j}, GarbagePixel)
+			}
+		}
+	}
+	return g
+}
+
+// spawns the piece, presses its keys and locks it where they leave it, without scoring any drop points
+func place(t *testing.T, g *Game, p placement) {
+	t.Helper()
+	g.CurrentPiece = g.newPiece(p.tetro)
+	g.LastMoveRotation = false
+	for _, key := range p.keys {
+		moved := false
+		switch key {
+		case 'L':
+			moved = g.MoveLeft()
+		case 'R':
+			moved = g.MoveRight()
+		case 'C':
+			moved = g.RotateClockWise()
+		case 'A':
+			moved = g.RotateCounterClockWise()
+		case 'F':
+			moved = g.Rotate180()
+		case 'D':
+			for g.GravityDrop() {
+				moved = true
+			}
+		}
+		if !moved {
+			t.Fatalf("piece %d couldn't do %c of %s", p.tetro, key, p.keys)
+		}
+	}
+	g.LockPiece()
+}
+
+func TestScoring(t *testing.T) {
+	var (
+		T = Tetro(5)
+		I = Tetro(4)
+		O = Tetro(1)
+
+		// an I standing up in the right hand column, and an O in the two right hand columns
+		wellI = placement{I, "CRRRRRD"}
+		wellO = placement{O, "RRRRD"}
+
+		// a T turned into a slot that leaves the row above it behind
+		tsdRows = []string{"XXXX.XXXXX", "XXX...XXXX", "XXXX......"}
+		tsd     = placement{T, "LCDC"}
+
+		// four rows with the right hand column empty, with something left above them when leftover is true
+		tetrisRows = func(leftover bool) []string {
+			rows := []string{"XXXXXXXXX.", "XXXXXXXXX.", "XXXXXXXXX.", "XXXXXXXXX."}
+			if leftover {
+				rows = append(rows, "X.........")
+			}
+			return rows
+		}
+	)
+	tests := []struct {
+		name   string
+		rows   []string
+		pieces []placement
+
+		// the level, and whether the last clear was difficult and how many pieces in a row had cleared lines before the first piece
+		level      int
+		backToBack bool
+		combo      int
+
+		// what the last piece did, checked along with how much it added to the score
+		want ClearEvent
+
+		labels []string
+	}{
+		{
+			name:   "single",
+			rows:   []string{"XXXXXXXXX."},
+			pieces: []placement{wellI},
+			want:   ClearEvent{Tetro: I, Lines: 1, Points: 100},
+			labels: []string{"SINGLE"},
+		},
+		{
+			name:   "tetris",
+			rows:   tetrisRows(true),
+			pieces: []placement{wellI},
+			want:   ClearEvent{Tetro: I, Lines: 4, Points: 800},
+			labels: []string{"TETRIS"},
+		},
+		{
+			name:   "t-spin double",
+			rows:   tsdRows,
+			pieces: []placement{tsd},
+			want:   ClearEvent{Tetro: T, Lines: 2, TSpin: TSpinFull, Points: 1200},
+			labels: []string{"T-SPIN DOUBLE"},
+		},
+		{
+			name:   "t-spin mini single",
+			rows:   []string{"XXXXXXXX..", "XXXXXXXXX."},
+			pieces: []placement{{T, "RRRDA"}},
+			want:   ClearEvent{Tetro: T, Lines: 1, TSpin: TSpinMini, Points: 200},
+			labels: []string{"T-SPIN MINI SINGLE"},
+		},
+		{
+			name:   "t-spin mini without a clear",
+			rows:   []string{"XXXXXX.XX.", ".XXXXXXXX.", "X....X..X."},
+			pieces: []placement{{T, "RRRDA"}},
+			want:   ClearEvent{Tetro: T, TSpin: TSpinMini, Points: 100},
+			labels: []string{"T-SPIN MINI"},
+		},
+		{
+			name:       "back-to-back tetris",
+			rows:       tetrisRows(true),
+			pieces:     []placement{wellI},
+			backToBack: true,
+			want:       ClearEvent{Tetro: I, Lines: 4, BackToBack: true, Points: 1200},
+			labels:     []string{"TETRIS", "B2B"},
+		},
+		{
+			name:       "back-to-back t-spin double",
+			rows:       tsdRows,
+			pieces:     []placement{tsd},
+			backToBack: true,
+			want:       ClearEvent{Tetro: T, Lines: 2, TSpin: TSpinFull, BackToBack: true, Points: 1800},
+			labels:     []string{"T-SPIN DOUBLE", "B2B"},
+		},
+		{
+			name:       "a double doesn't get back-to-back",
+			rows:       []string{"XXXXXXXX..", "XXXXXXXX..", "X........."},
+			pieces:     []placement{wellO},
+			backToBack: true,
+			want:       ClearEvent{Tetro: O, Lines: 2, Points: 300},
+			labels:     []string{"DOUBLE"},
+		},
+		{
+			name:   "two tetrises in a row",
+			rows:   append(tetrisRows(false), tetrisRows(true)...),
+			pieces: []placement{wellI, wellI},
+			want:   ClearEvent{Tetro: I, Lines: 4, BackToBack: true, Combo: 1, Points: 1200 + 50},
+			labels: []string{"TETRIS", "B2B", "1 COMBO"},
+		},
+		{
+			name:   "combo",
+			rows:   []string{"XXXXXXXX..", "XXXXXXXX..", "XXXXXXXX..", "XXXXXXXX..", "XXXXXXXX..", "XXXXXXXX..", "X........."},
+			pieces: []placement{wellO, wellO, wellO},
+			want:   ClearEvent{Tetro: O, Lines: 2, Combo: 2, Points: 300 + 100},
+			labels: []string{"DOUBLE", "2 COMBO"},
+		},
+		{
+			name:   "a piece that clears nothing ends the combo",
+			rows:   []string{"XXXXXXXX..", "XXXXXXXX.."},
+			pieces: []placement{{O, "LLLLD"}, wellO},
+			combo:  3,
+			want:   ClearEvent{Tetro: O, Lines: 2, Points: 300},
+			labels: []string{"DOUBLE"},
+		},
+		{
+			name:   "perfect clear",
+			rows:   []string{"XXXXXXXX..", "XXXXXXXX.."},
+			pieces: []placement{wellO},
+			want:   ClearEvent{Tetro: O, Lines: 2, PerfectClear: true, Points: 300 + 1200},
+			labels: []string{"DOUBLE", "PERFECT CLEAR"},
+		},
+		{
+			name:       "back-to-back tetris perfect clear",
+			rows:       tetrisRows(false),
+			pieces:     []placement{wellI},
+			backToBack: true,
+			want:       ClearEvent{Tetro: I, Lines: 4, BackToBack: true, PerfectClear: true, Points: 1200 + 3200},
+			labels:     []string{"TETRIS", "B2B", "PERFECT CLEAR"},
+		},
+		{
+			name:   "points go up with the level",
+			rows:   tsdRows,
+			pieces: []placement{tsd},
+			level:  3,
+			want:   ClearEvent{Tetro: T, Lines: 2, TSpin: TSpinFull, Points: 3 * 1200, Level: 3},
+			labels: []string{"T-SPIN DOUBLE"},
+		},
+	}
+	for _, tc := range tests {
+		g := newBoardGame(tc.rows)
+		g.Level = 1
+		if tc.level > 0 {
+			g.Level = tc.level
+		}
+		g.BackToBack = tc.backToBack
+		g.Combo = tc.combo - 1
+
+		var event ClearEvent
+		g.OnClear(func(e ClearEvent) {
+			event = e
+		})
+		score := 0
+		for _, p := range tc.pieces {
+			score = g.Score
+			place(t, &g, p)
+		}
+
+		want := tc.want
+		if want.Level == 0 {
+			want.Level = 1
+		}
+		if event != want {
+			t.Errorf("%s: got %+v, want %+v", tc.name, event, want)
+		}
+		if g.Score-score != want.Points {
+			t.Errorf("%s: the score went up by %d, want %d", tc.name, g.Score-score, want.Points)
+		}
+		if labels := event.Labels(); !reflect.DeepEqual(labels, tc.labels) {
+			t.Errorf("%s: labelled %q, want %q", tc.name, labels, tc.labels)
+		}
+	}
+}
+
+// the lowest row any pixel of the current piece is in
+func pieceBottom(g *Game) int {
+	bottom := g.PlayingBoard.Height
+	for _, p := range g.CurrentPiece.Shape {
+		if p.Row < bottom {
+			bottom = p.Row
+		}
+	}
+	return bottom
+}
+
+func TestDropPoints(t *testing.T) {
+	g := NewGame(1, NewBagRandomizer(1, 1))
+	g.SetNextTetroFromBag()
+	rows := pieceBottom(&g)
+	g.HardDrop()
+	if g.Score != rows*HardDropPoints {
+		t.Fatalf("a hard drop of %d rows scored %d, want %d", rows, g.Score, rows*HardDropPoints)
+	}
+
+	// soft dropping part of the way down, one step at a time so nothing locks
+	g = NewGame(1, NewBagRandomizer(1, 1))
+	g.SetNextTetroFromBag()
+	top := pieceBottom(&g)
+	for i := 0; i < 5; i++ {
+		g.Step(10*time.Millisecond, Input{SoftDrop: true})
+	}
+	fallen := top - pieceBottom(&g)
+	if fallen == 0 || fallen == top {
+		t.Fatalf("the piece fell %d of %d rows, the soft drop should have taken it part of the way down", fallen, top)
+	}
+	if g.Score != fallen*SoftDropPoints {
+		t.Fatalf("a soft drop of %d rows scored %d, want %d", fallen, g.Score, fallen*SoftDropPoints)
+	}
+}
+
+func TestHalfTurnLastKick(t *testing.T) {
+	T := Tetro(5)
+
+	// a 180 that only fits with its last kick, dropping the T a row
+	g := newBoardGame([]string{"X.XX..X.XX", "X....XX..X", "..XX...XXX", "..X.XX..XX"})
+	g.CurrentPiece = g.newPiece(T)
+	for _, key := range "LLLLAD" {
+		switch key {
+		case 'L':
+			g.MoveLeft()
+		case 'A':
+			g.RotateCounterClockWise()
+		case 'D':
+			for g.GravityDrop() {
+			}
+		}
+	}
+	if !g.Rotate180() || g.LastKick != 4 || !g.LastHalfTurn {
+		t.Fatalf("the 180 used kick %d, half turn %v, want the last kick of a half turn", g.LastKick, g.LastHalfTurn)
+	}
+
+	// a T with three corners filled but only one in front stays a mini when the last kick was a 180's,
+	// only the last kick of a quarter turn makes it a full t-spin
+	g = newBoardGame([]string{"XXXXXX.XX.", ".XXXXXXXX.", "X....X..X."})
+	g.CurrentPiece = g.newPiece(T)
+	for _, key := range "RRRDA" {
+		switch key {
+		case 'R':
+			g.MoveRight()
+		case 'A':
+			g.RotateCounterClockWise()
+		case 'D':
+			for g.GravityDrop() {
+			}
+		}
+	}
+	g.LastKick = 4
+	g.LastHalfTurn = true
+	if tspin := g.detectTSpin(); tspin != TSpinMini {
+		t.Fatalf("after the last kick of a 180 the t-spin is %v, want a mini", tspin)
+	}
+	g.LastHalfTurn = false
+	if tspin := g.detectTSpin(); tspin != TSpinFull {
+		t.Fatalf("after the last kick of a quarter turn the t-spin is %v, want a full one", tspin)
+	}
+}
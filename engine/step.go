@@ -0,0 +1,170 @@
+package engine
+
+import "time"
+
+// Input is the state of the controls for a single step, a field is true while its key is held down,
+// the game works out for itself which keys were just pressed
+type Input struct {
+	Left                   bool
+	Right                  bool
+	SoftDrop               bool
+	HardDrop               bool
+	RotateClockWise        bool
+	RotateCounterClockWise bool
+	Rotate180              bool
+	Hold                   bool
+}
+
+// returns the keys that are held in this input but were not held in the last one
+func (in Input) pressedSince(last Input) Input {
+	return Input{
+		Left:                   in.Left && !last.Left,
+		Right:                  in.Right && !last.Right,
+		SoftDrop:               in.SoftDrop && !last.SoftDrop,
+		HardDrop:               in.HardDrop && !last.HardDrop,
+		RotateClockWise:        in.RotateClockWise && !last.RotateClockWise,
+		RotateCounterClockWise: in.RotateCounterClockWise && !last.RotateCounterClockWise,
+		Rotate180:              in.Rotate180 && !last.Rotate180,
+		Hold:                   in.Hold && !last.Hold,
+	}
+}
+
+// converts a number of milliseconds to a duration
+func millis(ms int) time.Duration {
+	return time.Duration(ms) * time.Millisecond
+}
+
+// advances the game by dt, applying the keys held in this step,
+// this moves the piece, makes it fall, locks it and clears lines,
+// nothing happens while the game is paused or has ended, not even noticing which keys are held
+func (g *Game) Step(dt time.Duration, in Input) {
+	if g.Paused || g.GameOver || g.Finished {
+		return
+	}
+	g.record(dt, in)
+	defer g.updateMode()
+
+	pressed := in.pressedSince(g.LastInput)
+	g.LastInput = in
+
+	g.PlayTime += dt
+
+	// DAS is worked out even when there's no piece so it can charge during the line clear delay
+	dir, moves := g.updateShift(dt, in, pressed)
+
+	// there's no piece while we wait for the line clear delay to end
+	if g.CurrentPiece == nil {
+		if !g.Handling.DASChargeDuringDelay {
+			g.DASTimer = 0
+		}
+		g.SpawnTimer -= dt
+		if g.SpawnTimer <= 0 {
+			g.spawnNext()
+		}
+		return
+	}
+
+	// if we just pressed hold then swap the current piece with the held piece
+	if pressed.Hold && g.CanHold {
+		g.HoldTetro()
+		g.FallProgress = 0
+	}
+	g.countKeys(in, pressed)
+
+	// shifting the piece left or right, moves is -1 when it should go all the way to the wall
+	for moves != 0 && g.shiftPiece(dir) {
+		moves--
+	}
+
+	// if we just pressed one of the rotate keys, rotate the piece if it can
+	if pressed.RotateClockWise {
+		g.RotateClockWise()
+	}
+	if pressed.RotateCounterClockWise {
+		g.RotateCounterClockWise()
+	}
+	if pressed.Rotate180 {
+		g.Rotate180()
+	}
+
+	// if we just pressed hard drop, drop the piece as far as it goes and lock it straight away
+	if pressed.HardDrop {
+		g.HardDrop()
+		return
+	}
+
+	// move the piece down naturally, or faster if we're holding soft drop,
+	// gravity adds up a fraction of a row each step and the piece falls once for every whole row,
+	// the first pixel of a soft drop falls as soon as the key is pressed
+	g.FallProgress += g.gravityCells(dt, in.SoftDrop)
+	if pressed.SoftDrop && g.FallProgress < 1 {
+		g.FallProgress = 1
+	}
+	for g.FallProgress >= 1 {
+		if !g.GravityDrop() {
+			g.FallProgress = 0
+			break
+		}
+		if in.SoftDrop {
+			g.Score += SoftDropPoints
+		}
+		g.FallProgress--
+	}
+
+	// the lock delay only counts down while the piece is resting on something
+	if g.lockDue(dt) {
+		g.LockPiece()
+	}
+}
+
+// drops the current piece as far as it goes and locks it straight away
+func (g *Game) HardDrop() {
+	for g.GravityDrop() {
+		g.Score += HardDropPoints
+	}
+	g.LockPiece()
+}
+
+// locks the current piece where it is, adding it to the stack, then clears any lines, scores them and spawns the next piece,
+// the game is over if the piece broke the top out rules
+func (g *Game) LockPiece() {
+	locked := g.CurrentPiece.Shape
+	for _, p := range locked {
+		g.PlayingBoard.Set(p, Pixel(g.CurrentPiece.Tetro))
+	}
+	tspin := g.detectTSpin()
+	g.judgeFinesse(tspin)
+	out := g.lockOut(locked)
+	level := g.Level
+	lines := g.check_lines()
+	g.PiecesPlaced++
+	g.scoreClear(g.CurrentPiece.Tetro, lines, tspin, level)
+	if out != "" {
+		g.topOut(out)
+		return
+	}
+
+	// garbage only rises when a piece locks without clearing anything, clears give the player a chance to cancel it
+	if lines == 0 && len(g.IncomingGarbage) > 0 {
+		g.riseGarbage()
+		if g.GameOver {
+			return
+		}
+	}
+
+	// clearing lines leaves a gap before the next piece appears
+	if lines > 0 && g.LineClearDelayMillis > 0 {
+		g.CurrentPiece = nil
+		g.SpawnTimer = millis(g.LineClearDelayMillis)
+		return
+	}
+	g.spawnNext()
+}
+
+// spawns the next piece from the queue and resets everything that was counting for the last one
+func (g *Game) spawnNext() {
+	g.SetNextTetroFromBag()
+	g.CanHold = true
+	g.FallProgress = 0
+	g.SpawnTimer = 0
+}
@@ -0,0 +1,105 @@
+package engine
+
+// GameOverReason is how a game was lost, it is empty until then
+type GameOverReason string
+
+const (
+	// the next piece appeared overlapping the stack
+	BlockOut GameOverReason = "Block out"
+
+	// a piece locked with every pixel of it above the visible part of the board
+	LockOut GameOverReason = "Lock out"
+
+	// a piece locked leaving the stack above the visible part of the board
+	PartialLockOut GameOverReason = "Partial lock out"
+
+	// garbage pushed the stack or the falling piece off the top of the board
+	GarbageOut GameOverReason = "Garbage out"
+)
+
+// TopOutRules are which of the guideline's ways of losing end the game,
+// garbage pushing the stack off the top of the board always does
+type TopOutRules struct {
+	// the next piece appearing overlapping the stack ends the game,
+	// when this is off the piece is moved up until it fits and the game only ends if it can't
+	BlockOut bool `json:"block_out"`
+
+	// a piece locking with all of it above the visible part of the board ends the game
+	LockOut bool `json:"lock_out"`
+
+	// a piece locking with any of it above the visible part of the board ends the game,
+	// unless clearing lines brings it back down
+	PartialLockOut bool `json:"partial_lock_out"`
+}
+
+// returns the top out rules with all of them on
+func DefaultTopOutRules() TopOutRules {
+	return TopOutRules{
+		BlockOut:       true,
+		LockOut:        true,
+		PartialLockOut: true,
+	}
+}
+
+// ends the game, remembering why
+func (g *Game) topOut(reason GameOverReason) {
+	g.GameOver = true
+	g.GameOverReason = reason
+}
+
+// checks the piece that just appeared isn't in the stack, moving it up out of the stack when block out is off,
+// the game is over if it still doesn't fit
+func (g *Game) checkSpawn() {
+	piece := g.CurrentPiece
+	if !g.TopOut.BlockOut {
+		for g.Collides(piece.Shape) && highestRow(piece.Shape) < g.PlayingBoard.Height-1 {
+			for i := range piece.Shape {
+				piece.Shape[i].Row++
+			}
+			piece.Pivot.Row += 2
+		}
+	}
+	if g.Collides(piece.Shape) {
+		g.topOut(BlockOut)
+	}
+}
+
+// works out if the piece that just locked breaks the lock out rules, this has to be called before lines are cleared,
+// lock out goes by where the piece locked and partial lock out by where its pixels end up once full rows are cleared,
+// returns the empty reason if the game can go on
+func (g *Game) lockOut(locked Shape) GameOverReason {
+	if g.TopOut.LockOut && lowestRow(locked) >= g.PlayingBoard.Visible {
+		return LockOut
+	}
+	if !g.TopOut.PartialLockOut {
+		return ""
+	}
+	full := g.PlayingBoard.fullRow()
+	for _, p := range locked {
+		// pixels in full rows are cleared along with them
+		if g.PlayingBoard.Rows[p.Row] == full {
+			continue
+		}
+		row := p.Row
+		for i := 0; i < p.Row; i++ {
+			if g.PlayingBoard.Rows[i] == full {
+				row--
+			}
+		}
+		if row >= g.PlayingBoard.Visible {
+			return PartialLockOut
+		}
+	}
+	return ""
+}
+
+// the highest row any pixel of a shape is on
+func highestRow(s Shape) int {
+	highest := -1
+	for _, p := range s {
+		if p.Row > highest {
+			highest = p.Row
+		}
+	}
+	return highest
+}
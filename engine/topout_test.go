@@ -0,0 +1,71 @@
+package engine
+
+import "testing"
+
+// makes a game with a column of garbage from the floor up to and including row top in the middle of the board,
+// where every piece spawns
+func newTowerGame(top int, rules TopOutRules) *Game {
+	g := NewGame(1, NewBagRandomizer(1, 1))
+	g.TopOut = rules
+	for i := 0; i <= top; i++ {
+		for j := 4; j <= 5; j++ {
+			g.PlayingBoard.Set(Point{i, j}, GarbagePixel)
+		}
+	}
+	return &g
+}
+
+func TestBlockOut(t *testing.T) {
+	g := newTowerGame(22, DefaultTopOutRules())
+	g.SetNextTetroFromBag()
+	if g.GameOverReason != BlockOut {
+		t.Fatalf("spawning into the stack ended the game with %q, want %q", g.GameOverReason, BlockOut)
+	}
+
+	// a stack reaching right up to where the piece spawns doesn't block it
+	g = newTowerGame(21, DefaultTopOutRules())
+	g.SetNextTetroFromBag()
+	if g.GameOver {
+		t.Fatalf("spawning right above the stack ended the game with %q", g.GameOverReason)
+	}
+
+	// without block out the piece would be moved up out of the stack, but it still tops out when there's no room left above
+	g = newTowerGame(HeightOfBoardInPixels-1, TopOutRules{})
+	g.SetNextTetroFromBag()
+	if g.GameOverReason != BlockOut {
+		t.Fatalf("spawning with no room ended the game with %q, want %q", g.GameOverReason, BlockOut)
+	}
+}
+
+func TestLockOut(t *testing.T) {
+	// the tower reaches the spawn rows, so the piece locks where it spawns, right above the visible part of the board
+	g := newTowerGame(NonHiddenPixelHeight+1, TopOutRules{BlockOut: true, LockOut: true})
+	g.CurrentPiece = Tetro(4).NewTetromino()
+	g.LockPiece()
+	if g.GameOverReason != LockOut {
+		t.Fatalf("locking above the visible board ended the game with %q, want %q", g.GameOverReason, LockOut)
+	}
+}
+
+func TestPartialLockOut(t *testing.T) {
+	rules := TopOutRules{PartialLockOut: true}
+
+	// an O on a tower reaching the top visible row sticks out above it
+	g := newTowerGame(NonHiddenPixelHeight-2, rules)
+	g.CurrentPiece = Tetro(1).NewTetromino()
+	g.HardDrop()
+	if g.GameOverReason != PartialLockOut {
+		t.Fatalf("locking partly above the visible board ended the game with %q, want %q", g.GameOverReason, PartialLockOut)
+	}
+
+	// unless a line clear under it brings it back down
+	g = newTowerGame(NonHiddenPixelHeight-2, rules)
+	for j := 0; j < WidthOfBoardInPixels; j++ {
+		g.PlayingBoard.Set(Point{0, j}, GarbagePixel)
+	}
+	g.CurrentPiece = Tetro(1).NewTetromino()
+	g.HardDrop()
+	if g.GameOver {
+		t.Fatalf("a piece brought back down by a line clear ended the game with %q", g.GameOverReason)
+	}
+}
@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Versus is a match between games that attack each other with garbage,
+// the last one left standing wins
+type Versus struct {
+	// the games in the match, in the order they are drawn
+	Players []*Game
+
+	// how much garbage each kind of clear sends
+	Attack AttackTable
+
+	// picks the hole column for each batch of garbage
+	rng *rand.Rand
+}
+
+// starts a match between players, every line clear sends garbage by the attack table to the next player still in,
+// the holes in the garbage come from seed
+func NewVersus(players []*Game, attack AttackTable, seed int64) *Versus {
+	v := &Versus{
+		Players: players,
+		Attack:  attack,
+		rng:     rand.New(rand.NewSource(seed)),
+	}
+	for i, g := range players {
+		i, g := i, g
+		g.OnClear(func(e ClearEvent) {
+			lines := g.CancelGarbage(v.Attack.Attack(e))
+			if target := v.target(i); target != nil && lines > 0 {
+				target.ReceiveGarbage(lines, v.rng.Intn(target.PlayingBoard.Width))
+			}
+		})
+	}
+	return v
+}
+
+// the player that player i attacks, the next one along that hasn't lost, nil if everyone else has lost
+func (v *Versus) target(i int) *Game {
+	for j := 1; j < len(v.Players); j++ {
+		g := v.Players[(i+j)%len(v.Players)]
+		if !g.GameOver {
+			return g
+		}
+	}
+	return nil
+}
+
+// advances every game by dt, each with its own input, nothing happens once the match is over
+func (v *Versus) Step(dt time.Duration, inputs []Input) {
+	if v.Over() {
+		return
+	}
+	for i, g := range v.Players {
+		g.Step(dt, inputs[i])
+	}
+}
+
+// pauses or unpauses every game in the match
+func (v *Versus) SetPaused(paused bool) {
+	for _, g := range v.Players {
+		g.Paused = paused
+	}
+}
+
+// the player that won, or -1 if the match is still going or everyone lost on the same step
+func (v *Versus) Winner() int {
+	winner := -1
+	for i, g := range v.Players {
+		if g.GameOver {
+			continue
+		}
+		if winner != -1 {
+			return -1
+		}
+		winner = i
+	}
+	if !v.Over() {
+		return -1
+	}
+	return winner
+}
+
+// is the match over, with at most one player left
+func (v *Versus) Over() bool {
+	left := 0
+	for _, g := range v.Players {
+		if !g.GameOver {
+			left++
+		}
+	}
+	return left <= 1
+}
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"image/color"
-	"strconv"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/faiface/pixel"
@@ -13,6 +15,8 @@ import (
 
 	"github.com/goki/freetype/truetype"
 	"golang.org/x/image/font/gofont/goregular"
+
+	"tetris/engine"
 )
 
 const (
@@ -22,6 +26,9 @@ const (
 	// padding to display the next piece next to the board (vertical)
 	SideWindowVerticalPadding = BoardHeight + Padding + BorderWidth - BoardHeight/4
 
+	// how far right of the next pieces the score and level are shown
+	SidePanelTextOffset = PixelScale * 6
+
 	// how wide is the border around the board
 	BorderWidth = 3
 
@@ -35,30 +42,212 @@ const (
 	Padding = BoardWidth / 20
 
 	// how many screen pixels wide/tall is a in-game pixel
-	PixelScale = BoardWidth / WidthOfBoardInPixels
-
-	// How many pixels wide is the board.
-	// pixels being the pixels defined in this game, not screen pixels
-	WidthOfBoardInPixels = 10
+	PixelScale = BoardWidth / engine.WidthOfBoardInPixels
 
-	// How many pixels tall is the board.
-	// pixels being the pixels defined in this game, not screen pixels
-	HeightOfBoardInPixels = 24
-
-	// How many pixels tall is the non-hidden part of the board.
-	// pixels being the pixels defined in this game, not screen pixels
-	NonHiddenPixelHeight = 20
+	// how many milliseconds a popup like "T-SPIN DOUBLE" stays on the screen
+	PopupMillis = 2000
 )
 
 var (
-	// line_cleared determines if a line was cleared or not
-	line_cleared bool
+	// the seed for the randomizer, two games with the same seed get the same tetros in the same order
+	seed_flag = flag.Int64("seed", 0, "seed for the piece randomizer, 0 picks one from the current time")
+
+	// which randomizer picks the tetros
+	randomizer_flag = flag.String("randomizer", "7bag", "piece randomizer, one of: "+strings.Join(engine.RandomizerNames, ", "))
+
+	// which pieces are dealt
+	pieces_flag = flag.String("pieces", "tetrominoes", "piece set, one of: "+strings.Join(engine.PieceSetNames, ", ")+", or a piece set file")
+
+	// how big the board is
+	board_flag = flag.String("board", "10x20", "board size as WIDTHxHEIGHT, the height not counting the hidden rows above it")
+
+	// the rules to play by
+	mode_flag = flag.String("mode", "marathon", "game mode, one of: "+strings.Join(engine.ModeNames, ", "))
+
+	// where to save a recording of the game when it ends
+	record_flag = flag.String("record", "", "save a replay of the game to this file")
+
+	// a replay to watch instead of playing
+	replay_flag = flag.String("replay", "", "watch the replay in this file instead of playing")
+
+	// the config file with the key bindings and handling
+	config_flag = flag.String("config", "", "config file with key bindings and handling (default $XDG_CONFIG_HOME/tetris/config.json)")
+
+	// play a versus match against a second player on the same keyboard
+	versus_flag = flag.Bool("versus", false, "two player versus, the second player uses the player2_keys from the config")
+
+	// the tetris-server to play on instead of playing by ourselves
+	connect_flag = flag.String("connect", "", "play online on the tetris-server at this address")
+
+	// the name other players see online
+	name_flag = flag.String("name", "Player", "your name when playing online")
+
+	// the room to join on the server
+	room_flag = flag.String("room", "lobby", "the room to join when playing online")
+
+	// watch a room instead of playing in it
+	spectate_flag = flag.Bool("spectate", false, "watch the room instead of playing when online")
+
+	// let the bot play instead of the keyboard
+	bot_flag = flag.Bool("bot", false, "let the bot play, in a versus match the bot is the second player")
+
+	// how fast the bot plays
+	bot_pps_flag = flag.Float64("bot-pps", 2, "how many pieces a second the bot places")
+
+	// the weights the bot plays with
+	bot_weights_flag = flag.String("bot-weights", "", "JSON file with the bot's weights, as written by tetris-bench -tune")
+
+	// an external bot to play with instead of the built in one
+	bot_command_flag = flag.String("bot-command", "", "command that runs a bot speaking the Tetris Bot Protocol, like cold-clear, to play with instead of the built in bot")
 
-	// the ghost_tetro contains the coordinates of the ghost pixel on screen
-	ghost_tetro Shape
+	// start again whenever a piece is placed with more keys than it needed
+	finesse_restart_flag = flag.Bool("finesse-restart", false, "finesse training, the game starts again whenever a piece is placed with more keys than it needed")
+
+	// where to save the stats of the game when it ends
+	stats_flag = flag.String("stats", "", "save the stats of the game to this file as JSON when it ends")
+
+	// print the high score table instead of playing
+	scores_flag = flag.Bool("scores", false, "print the high score table and exit")
 )
 
-func run() {
+// loads the config file given with -config, or the default one, along with the key bindings for each player
+func read_config() (Config, []keyBindings, error) {
+	path := *config_flag
+	if path == "" {
+		var err error
+		if path, err = default_config_path(); err != nil {
+			return Config{}, nil, err
+		}
+	}
+	cfg, err := load_config(path)
+	if err != nil {
+		return Config{}, nil, err
+	}
+	var keys []keyBindings
+	for _, player_keys := range []map[string][]string{cfg.Keys, cfg.Player2Keys} {
+		k, err := bindings(player_keys)
+		if err != nil {
+			return Config{}, nil, fmt.Errorf("%s: %w", path, err)
+		}
+		keys = append(keys, k)
+	}
+	if *versus_flag {
+		if err := shared_keys(cfg.Keys, cfg.Player2Keys); err != nil {
+			return Config{}, nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return cfg, keys, nil
+}
+
+// sets up the game to play, or the replay player if we're watching a replay
+func new_game(cfg Config) (*engine.Game, *engine.ReplayPlayer, error) {
+	if *replay_flag != "" {
+		f, err := os.Open(*replay_flag)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer f.Close()
+		replay, err := engine.ReadReplay(f)
+		if err != nil {
+			return nil, nil, err
+		}
+		player, err := engine.NewReplayPlayer(replay)
+		if err != nil {
+			return nil, nil, err
+		}
+		return player.Game, player, nil
+	}
+
+	seed := *seed_flag
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	game, err := new_player_game(cfg, seed)
+	if err != nil {
+		return nil, nil, err
+	}
+	if *record_flag != "" {
+		game.StartRecording()
+	}
+	game.SetNextTetroFromBag()
+	return game, nil, nil
+}
+
+// makes a game with the settings from the flags and config, ready for its first piece to spawn
+func new_player_game(cfg Config, seed int64) (*engine.Game, error) {
+	pieces, err := engine.LoadPieceSet(*pieces_flag)
+	if err != nil {
+		return nil, err
+	}
+	width, height, err := parse_board_size(*board_flag)
+	if err != nil {
+		return nil, err
+	}
+	if err := engine.CheckBoardSize(width, height, pieces); err != nil {
+		return nil, err
+	}
+	randomizer, err := engine.NewRandomizer(*randomizer_flag, seed, pieces.Len())
+	if err != nil {
+		return nil, err
+	}
+	mode, err := engine.NewMode(*mode_flag)
+	if err != nil {
+		return nil, err
+	}
+	game := engine.NewGame(seed, randomizer)
+	game.Pieces = pieces
+	// the board has to be the right size before the mode fills it with garbage
+	game.SetBoardSize(width, height)
+	game.SetMode(mode)
+	game.Handling = cfg.Handling
+	game.Finesse180 = len(cfg.Keys[ActionRotate180]) > 0
+	game.LineClearDelayMillis = cfg.LineClearDelayMillis
+	game.LockDelayMillis = cfg.LockDelayMillis
+	game.LockMode = cfg.LockMode
+	game.MaxLockResets = cfg.MaxLockResets
+	game.TopOut = cfg.TopOut
+	game.Gravity = cfg.Gravity
+	game.LevelGoal = cfg.LevelGoal
+	game.QueueLength = cfg.QueueLength
+	game.SetStartLevel(cfg.StartLevel)
+	return &game, nil
+}
+
+// reads a board size written as WIDTHxHEIGHT, like 10x20
+func parse_board_size(s string) (int, int, error) {
+	var width, height int
+	if n, err := fmt.Sscanf(s, "%dx%d", &width, &height); err != nil || n != 2 {
+		return 0, 0, fmt.Errorf("board size %q should be written as WIDTHxHEIGHT, like 10x20", s)
+	}
+	return width, height, nil
+}
+
+// sets up a versus match between the game and one more player, who gets the same pieces in the same order
+func new_versus(cfg Config, game *engine.Game) (*engine.Versus, error) {
+	other, err := new_player_game(cfg, game.Seed)
+	if err != nil {
+		return nil, err
+	}
+	other.Finesse180 = len(cfg.Player2Keys[ActionRotate180]) > 0
+	other.SetNextTetroFromBag()
+	return engine.NewVersus([]*engine.Game{game, other}, cfg.Attack, game.Seed), nil
+}
+
+// writes the recording of the game to the file given with -record
+func save_replay(game *engine.Game) error {
+	f, err := os.Create(*record_flag)
+	if err != nil {
+		return err
+	}
+	if err := game.Recording.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// opens a window as big as the screen, along with the atlas text is drawn with
+func open_window() (*pixelgl.Window, *text.Atlas) {
 	ttf, err := truetype.Parse(goregular.TTF)
 	if err != nil {
 		panic(err)
@@ -68,275 +257,295 @@ func run() {
 	})
 
 	monitor_width, monitor_height := pixelgl.PrimaryMonitor().PhysicalSize()
-	if err != nil {
-		panic(err)
-	}
 
-	cfg := pixelgl.WindowConfig{
+	win_cfg := pixelgl.WindowConfig{
 		Title:  "Tetris",
 		Bounds: pixel.R(0, 0, monitor_width, monitor_height),
 		VSync:  true,
 	}
 
-	win, err := pixelgl.NewWindow(cfg)
+	win, err := pixelgl.NewWindow(win_cfg)
 	if err != nil {
 		panic(err)
 	}
-	defer win.Destroy()
+	return win, text.NewAtlas(face, text.ASCII)
+}
 
-	var (
-		// atlas for displaying text on the screen
-		atlas = text.NewAtlas(face, text.ASCII)
+func run() {
+	cfg, keys, err := read_config()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if *connect_flag != "" {
+		run_online(cfg, keys[0])
+		return
+	}
+	game, player, err := new_game(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	games := []*engine.Game{game}
+	var match *engine.Versus
+	if *versus_flag {
+		if match, err = new_versus(cfg, game); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		games = match.Players
+	}
 
-		// main game struct
-		game = NewGame()
+	win, atlas := open_window()
+	defer win.Destroy()
 
+	var (
 		// imdraw struct to draw shapes on the screen
 		imd = imdraw.New(nil)
 
-		// can_drop determines if we should lock the piece
-		can_drop = false
+		// last frame is when the previous frame started, used to tell the game how much time has passed
+		last_frame = time.Now()
 
-		// hard_dropped determines if we just hard dropped a piece
-		hard_dropped = false
+		// every board on the screen, one for each player
+		views []*boardView
 
-		// lock time determines how many milliseconds shouldve passed before we lock the piece
-		lock_time time.Time
+		// is the replay we're watching paused
+		replay_paused = false
 
-		// drop time determines how many milliseconds shouldve passed before we drop the piece a pixel
-		drop_time time.Time
+		// the high score table and where it is saved
+		table, scores_path = load_scores()
 
-		// move time determines how many milliseconds shouldve passed before we can move the piece
-		move_time time.Time
-	)
+		// the screen shown once the game is over, nil while it's still going
+		game_over *gameOverScreen
 
-	game.GenerateNewBag()
-	game.SetNextTetroFromBag()
+		// the bot playing the last game on the screen when playing with -bot
+		autoplay *botPlayer
 
-	for !win.Closed() {
-		// calculating the center of the screen every time we loop
-		WidthSubForFullScreen := (win.Bounds().W() / 2) - (BoardWidth / 2)
-		HeightSubForFullScreen := (win.Bounds().H() / 2) - (BoardHeight / 2)
+		// is the stats panel shown next to each board
+		show_stats = false
+	)
 
-		// checking if we paused/unpaused the game
-		if win.JustPressed(pixelgl.KeyEscape) {
-			game.Paused = !game.Paused
+	if *bot_flag || *bot_command_flag != "" {
+		if autoplay, err = new_bot_player(games[len(games)-1]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
 		}
-		// if the game is not paused go through the normal game loop
-		if !game.Paused {
-			// if we lost, break this loop to close the window
-			if game.GameOver {
-				break
-			}
-			// resetting the graphics
-			imd.Reset()
-
-			// if a line was not just cleared and we didnt just hard drop a piece
-			// we check these so we cant like rotate a piece if its supposed to be locked in place
-			if !line_cleared && !hard_dropped {
-				// if any of the movement keys were just pressed set the lock_time to when that key was pressed
-				if win.JustPressed(pixelgl.KeyRight) ||
-					win.JustPressed(pixelgl.KeyLeft) ||
-					win.JustPressed(pixelgl.KeyDown) {
-
-					lock_time = time.Now()
-				}
+		defer autoplay.close()
+	}
 
-				// if we pressed right, move the piece right if it can
-				if win.Pressed(pixelgl.KeyRight) {
-					if !game.CheckIfSomethingRight() &&
-						time.Now().After(move_time.Add(time.Millisecond*time.Duration(75))) {
+	for _, g := range games {
+		views = append(views, new_board_view(g, cfg.Attack))
+	}
 
-						move_time = time.Now()
-						game.MoveRight()
-					}
-				}
-				// if we pressed left, move the piece left if it can
-				if win.Pressed(pixelgl.KeyLeft) {
-					if !game.CheckIfSomethingLeft() &&
-						time.Now().After(move_time.Add(time.Millisecond*time.Duration(75))) {
+	// a game played by yourself can be saved from the pause menu, and carried on from the menu shown when the game starts
+	can_save := player == nil && match == nil && autoplay == nil
+	pause_menu := new_menu("Resume", "Save & Quit")
+	var start_menu *menu
+	if can_save && has_saved_game() {
+		start_menu = new_menu("Continue", "New game")
+	}
 
-						move_time = time.Now()
-						game.MoveLeft()
-					}
-				}
-				// if we're pressing down, start falling down faster
-				if win.Pressed(pixelgl.KeyDown) {
-					can_drop = game.GravityDrop()
-					if !can_drop &&
-						time.Now().After(lock_time.Add(time.Millisecond*time.Duration(200))) {
+	// in finesse training a fault is noticed when the piece locks and the game starts again after the step
+	faulted := false
+	watch_finesse := func(g *engine.Game) {
+		g.OnClear(func(e engine.ClearEvent) {
+			faulted = faulted || e.FinesseFaults > 0
+		})
+	}
+	if *finesse_restart_flag {
+		watch_finesse(game)
+	}
 
-						can_drop = false
-					}
-				}
-				// if we just pressed space, hard drop
-				if win.JustPressed(pixelgl.KeySpace) {
-					for game.GravityDrop() {
-					}
-					can_drop = false
-					hard_dropped = true
-				}
-				// if we just pressed up, rotate the piece if it can
-				if win.JustPressed(pixelgl.KeyUp) {
-					if game.RotateClockWise() &&
-						time.Now().After(lock_time.Add(time.Millisecond*time.Duration(75))) &&
-						!hard_dropped {
+	for !win.Closed() {
+		// working out where the first board goes every time we loop, the others go next to it
+		WidthSubForFullScreen, HeightSubForFullScreen := board_offset(win, 0, len(views))
+
+		// resetting the graphics
+		imd.Reset()
 
-						lock_time = time.Now()
+		dt := time.Since(last_frame)
+		last_frame = time.Now()
+
+		if start_menu != nil {
+			// nothing moves until we've picked between carrying on the saved game and the new one
+			switch start_menu.update(win) {
+			case 0:
+				if resumed, collected, err := load_saved_game(); err != nil {
+					fmt.Fprintln(os.Stderr, "loading saved game:", err)
+				} else {
+					game = resumed
+					if *record_flag != "" {
+						// the replay starts from where the game was saved
+						game.StartRecording()
 					}
-				}
-				// if we just pressed C then hold the current piece
-				if win.JustPressed(pixelgl.KeyC) {
-					if time.Now().After(move_time.Add(time.Millisecond * time.Duration(75))) {
-						game.HoldTetro()
-						move_time = time.Now()
-						lock_time = time.Now()
-						game.CanHold = false
+					if *finesse_restart_flag {
+						watch_finesse(game)
 					}
+					views = []*boardView{new_board_view(game, cfg.Attack)}
+					views[0].stats.Resume(collected)
 				}
-				// if a line was cleared and we just hard dropped then reset timers and set a new current piece
-			} else if line_cleared && hard_dropped {
-				line_cleared = false
-				hard_dropped = false
-				lock_time = time.Now()
-				drop_time = time.Now()
-				game.SetNextTetroFromBag()
-				game.CanHold = true
+				start_menu = nil
+			case 1:
+				// starting a new game throws the saved one away, so it isn't offered again
+				if err := remove_saved_game(); err != nil {
+					fmt.Fprintln(os.Stderr, "removing saved game:", err)
+				}
+				start_menu = nil
+			}
+		} else if player != nil {
+			// when watching a replay, escape closes the window, space pauses,
+			// right and left speed it up and slow it down, and period steps a frame while paused
+			if win.JustPressed(pixelgl.KeyEscape) {
+				break
+			}
+			if win.JustPressed(pixelgl.KeySpace) {
+				replay_paused = !replay_paused
+			}
+			if win.JustPressed(pixelgl.KeyRight) && player.Speed < 16 {
+				player.Speed *= 2
+			}
+			if win.JustPressed(pixelgl.KeyLeft) && player.Speed > 0.25 {
+				player.Speed /= 2
 			}
-			// if now is after the move timer, then move the piece down naturally
-			if time.Now().After(drop_time.Add(time.Millisecond * time.Duration(game.FallingSpeedMillis/game.Level))) {
-				// if a line has not been cleared, drop the piece
-				if !line_cleared {
-					can_drop = game.GravityDrop()
-					drop_time = time.Now()
+			if !replay_paused {
+				player.Advance(dt)
+			} else if win.JustPressed(pixelgl.KeyPeriod) {
+				player.StepFrame()
+			}
+		} else if match != nil {
+			// once only one player is left, enter or escape closes the window,
+			// until then the pause key of the first player pauses everyone
+			if match.Over() {
+				if win.JustPressed(pixelgl.KeyEnter) || win.JustPressed(pixelgl.KeyEscape) {
+					break
+				}
+			} else {
+				if keys[0].just_pressed(win, ActionPause) {
+					match.SetPaused(!game.Paused)
 				}
-				// otherwise check if the game should end, and if it shouldnt set a new piece
-				if !can_drop && time.Now().After(lock_time.Add(time.Millisecond*time.Duration(200))) {
-					game.CanHold = true
-					// we are checking if every row on the board has a pixel thats taken
-					// if thats true then the game is over, because we've filled the board
-					all_lines_filled := 0
-					for i := 0; i < len(game.PlayingBoard); i++ {
-						for j := 0; j < WidthOfBoardInPixels; j++ {
-							if game.PlayingBoard[Point{i, j}] != Pixel(0) {
-								all_lines_filled = i
-							}
-						}
-						if all_lines_filled >= NonHiddenPixelHeight &&
-							!line_cleared &&
-							time.Now().After(lock_time.Add(time.Millisecond*time.Duration(200))) {
-							game.GameOver = true
-						}
+				inputs := make([]engine.Input, len(match.Players))
+				for i := range inputs {
+					if autoplay == nil || match.Players[i] != autoplay.game {
+						inputs[i] = keys[i].input(win)
 					}
-
-					game.SetNextTetroFromBag()
-					hard_dropped = false
-					lock_time = time.Now()
 				}
-
-			}
-			// checking if any lines were cleared
-			line_cleared = false
-			if !can_drop {
-				line_cleared = game.check_lines()
+				match.Step(dt, inputs)
+				if autoplay != nil {
+					autoplay.think(dt)
+				}
 			}
-		}
-
-		// getting the coordinates of the ghost tetro
-		for i := 0; i < HeightOfBoardInPixels; i++ {
-			shape := make(Shape, 0)
-			for j := 0; j < len(game.CurrentPiece.Shape); j++ {
-				shape = append(shape, Point{Col: game.CurrentPiece.Shape[j].Col, Row: game.CurrentPiece.Shape[j].Row - i})
+		} else if game.GameOver || game.Finished {
+			// once we've lost or reached the goal, show the game over screen until it's done and then close the window
+			if game_over == nil {
+				game_over = new_game_over_screen(game, table, views[0].stats.Stats())
 			}
-
-			if game.CheckIfSomethingUnder(&shape) {
-				ghost_tetro = shape
+			if game_over.update(win, table, scores_path) {
 				break
 			}
-		}
+		} else {
+			// checking if we paused/unpaused the game
+			if keys[0].just_pressed(win, ActionPause) {
+				game.Paused = !game.Paused
+				pause_menu.selected = 0
+			}
 
-		// setting all the pixels
-		for i := 0; i < HeightOfBoardInPixels; i++ {
-			for j := 0; j < WidthOfBoardInPixels; j++ {
-				if ContainsShape(ghost_tetro, &Point{i, j}) && !ContainsShape(game.CurrentPiece.Shape, &Point{i, j}) {
-					imd.Color = pixel.ToRGBA(Tetro(8).TetroToColor())
-				} else if i < NonHiddenPixelHeight {
-					imd.Color = pixel.ToRGBA(Tetro(game.PlayingBoard[Point{i, j}]).TetroToColor())
-				} else {
-					imd.Color = pixel.ToRGBA(color.Transparent)
+			// the pause menu carries on playing, or saves the game to carry on next time and closes the window
+			if game.Paused && can_save {
+				choice := pause_menu.update(win)
+				if choice == 0 {
+					game.Paused = false
 				}
-				imd.Push(pixel.V(float64(PixelScale*j+Padding+(BorderWidth*2)+int(WidthSubForFullScreen)), float64(PixelScale*i+Padding+(BorderWidth*2)+int(HeightSubForFullScreen))))
+				if choice == 1 {
+					if err := save_game(game, views[0].stats.Stats()); err != nil {
+						fmt.Fprintln(os.Stderr, "saving game:", err)
+					} else {
+						break
+					}
+				}
+			}
 
-				imd.Push(pixel.V(float64((PixelScale*j)+PixelScale+Padding/2+BorderWidth/2+int(WidthSubForFullScreen)), float64((PixelScale*i)+PixelScale+Padding/2+BorderWidth/2+int(HeightSubForFullScreen))))
+			if autoplay != nil {
+				// the bot places the pieces, the game still needs stepping for gravity and spawning
+				game.Step(dt, engine.Input{})
+				autoplay.think(dt)
+			} else {
+				// forwarding the keys we're holding to the game, which moves, drops and locks the piece
+				game.Step(dt, keys[0].input(win))
+			}
 
-				imd.Rectangle(0)
+			// a fault in finesse training starts a new game, the finesse counts and stats carry on so they cover the whole session
+			if faulted {
+				old, collected := game, views[0].stats.Stats()
+				if game, _, err = new_game(cfg); err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					os.Exit(2)
+				}
+				game.FinessePieces, game.FinessePerfect, game.FinesseFaults = old.FinessePieces, old.FinessePerfect, old.FinesseFaults
+				watch_finesse(game)
+				views = []*boardView{new_board_view(game, cfg.Attack)}
+				views[0].stats.Resume(collected)
+				faulted = false
 			}
 		}
 
-		// showing the border of the board
-		imd.Color = color.RGBA{100, 100, 100, 100}
-		imd.Push(pixel.V(Padding+WidthSubForFullScreen, Padding+HeightSubForFullScreen))
-		imd.Push(pixel.V(BoardWidth+Padding+BorderWidth+WidthSubForFullScreen, BoardHeight+Padding+BorderWidth+HeightSubForFullScreen))
-		imd.Rectangle(BorderWidth)
-
-		// checking if the bag is emtpy so we can show the next piece
-		if len(game.Current7Bag) < 1 || game.Current7Bag == nil {
-			game.GenerateNewBag()
+		// the stats key shows and hides the stats panel, which is always shown next to the game over screen
+		if keys[0].just_pressed(win, ActionStats) {
+			show_stats = !show_stats
 		}
-
-		// showing the next piece
-		shape := game.Current7Bag[0].Tetro.TetroToNewShape()
-		for i := 0; i < len(shape); i++ {
-			shape[i].Col -= 4
-			shape[i].Row -= 22
+		for i, v := range views {
+			v.show_stats = show_stats || (i == 0 && game_over != nil)
+			offset_x, offset_y := board_offset(win, i, len(views))
+			v.draw(win, imd, atlas, offset_x, offset_y)
 		}
-		for i := 0; i < 4; i++ {
-			for j := 0; j < 4; j++ {
-				imd.Color = pixel.ToRGBA(game.Current7Bag[0].Tetro.TetroToColor())
-				imd.Push(pixel.V(float64(SideWindowHorizontalPadding+shape[i].Col*PixelScale+PixelScale+Padding+int(WidthSubForFullScreen)), float64(SideWindowVerticalPadding+PixelScale+shape[i].Row*PixelScale+Padding+int(HeightSubForFullScreen))))
-				imd.Push(pixel.V(float64(PixelScale+PixelScale+SideWindowHorizontalPadding+shape[i].Col*PixelScale+int(WidthSubForFullScreen)), float64(PixelScale+PixelScale+SideWindowVerticalPadding+shape[i].Row*PixelScale+int(HeightSubForFullScreen))))
-				imd.Rectangle(0)
+
+		// showing who won a versus match, or that it's paused, over each board
+		if match != nil && (match.Over() || game.Paused) {
+			for i := range match.Players {
+				txt := text.New(board_top_left(board_offset(win, i, len(views))), atlas)
+				switch {
+				case !match.Over():
+					fmt.Fprintln(txt, "Paused")
+				case match.Winner() == i:
+					fmt.Fprintf(txt, "PLAYER %d WINS\n\nPress enter to quit\n", i+1)
+				default:
+					fmt.Fprintln(txt, "GAME OVER")
+					fmt.Fprintln(txt, match.Players[i].GameOverReason)
+				}
+				txt.Draw(win, pixel.IM)
 			}
 		}
-		// displaying next for the next piece
-		txt := text.New(pixel.V(float64(SideWindowHorizontalPadding+PixelScale+WidthSubForFullScreen), float64(PixelScale+PixelScale+SideWindowVerticalPadding+2*PixelScale+HeightSubForFullScreen)), atlas)
-		fmt.Fprint(txt, "Next")
-		txt.Draw(win, pixel.IM)
-
-		// displaying the score text
-		txt = text.New(pixel.V(float64(SideWindowHorizontalPadding+PixelScale+WidthSubForFullScreen), float64(PixelScale+PixelScale+SideWindowVerticalPadding+2*PixelScale)), atlas)
-		fmt.Fprint(txt, "Score")
-		txt.Draw(win, pixel.IM)
-		txt = text.New(pixel.V(float64(SideWindowHorizontalPadding+PixelScale+WidthSubForFullScreen), float64(PixelScale+PixelScale+SideWindowVerticalPadding+2*PixelScale-PixelScale)), atlas)
-		fmt.Fprint(txt, strconv.Itoa(game.Score))
-		txt.Draw(win, pixel.IM)
-
-		// displaying the level text
-		txt = text.New(pixel.V(float64(SideWindowHorizontalPadding+PixelScale+WidthSubForFullScreen), float64(PixelScale+PixelScale+SideWindowVerticalPadding+2*PixelScale-PixelScale-PixelScale)), atlas)
-		fmt.Fprint(txt, "Level")
-		txt.Draw(win, pixel.IM)
-		txt = text.New(pixel.V(float64(SideWindowHorizontalPadding+PixelScale+WidthSubForFullScreen), float64(PixelScale+PixelScale+SideWindowVerticalPadding+2*PixelScale-PixelScale-PixelScale-PixelScale)), atlas)
-		fmt.Fprint(txt, strconv.Itoa(game.Level))
-		txt.Draw(win, pixel.IM)
-
-		// showing the held piece
-		if game.HeldPiece != 0 {
-			shape := Tetro(game.HeldPiece).TetroToNewShape()
-			for i := 0; i < len(shape); i++ {
-				shape[i].Col -= 4
-				shape[i].Row -= 22
+
+		// showing the menu to carry on a saved game, the game over screen, or the pause menu and the high score table
+		// while paused, over the board of a single player game
+		if start_menu != nil {
+			txt := text.New(board_top_left(WidthSubForFullScreen, HeightSubForFullScreen), atlas)
+			fmt.Fprintln(txt, "Saved game found")
+			fmt.Fprintln(txt)
+			start_menu.draw(txt)
+			txt.Draw(win, pixel.IM)
+		} else if match == nil && game_over != nil {
+			game_over.draw(win, atlas, board_top_left(WidthSubForFullScreen, HeightSubForFullScreen), table)
+		} else if match == nil && game.Paused {
+			txt := text.New(board_top_left(WidthSubForFullScreen, HeightSubForFullScreen), atlas)
+			fmt.Fprintln(txt, "Paused")
+			fmt.Fprintln(txt)
+			if can_save {
+				pause_menu.draw(txt)
+				fmt.Fprintln(txt)
 			}
+			draw_scores(txt, table, score_ruleset(game), -1)
+			txt.Draw(win, pixel.IM)
+		}
 
-			for i := 0; i < 4; i++ {
-				for j := 0; j < 4; j++ {
-					imd.Color = pixel.ToRGBA(Tetro(game.HeldPiece).TetroToColor())
-					imd.Push(pixel.V(float64(-(SideWindowHorizontalPadding/2)+(shape[i].Col*PixelScale)+(PixelScale+Padding)+int(WidthSubForFullScreen)), float64((SideWindowVerticalPadding)+(PixelScale+Padding)+(shape[i].Row*PixelScale)+int(HeightSubForFullScreen))))
-					imd.Push(pixel.V(float64(PixelScale+PixelScale-SideWindowHorizontalPadding/2+shape[i].Col*PixelScale+int(WidthSubForFullScreen)), float64(PixelScale+PixelScale+SideWindowVerticalPadding+shape[i].Row*PixelScale+int(HeightSubForFullScreen))))
-					imd.Rectangle(0)
-				}
+		// showing how fast the replay is playing
+		if player != nil {
+			txt := text.New(pixel.V(float64(-SideWindowHorizontalPadding/2+PixelScale+WidthSubForFullScreen), float64(BoardHeight+Padding+HeightSubForFullScreen)), atlas)
+			fmt.Fprintf(txt, "Replay x%g", player.Speed)
+			if replay_paused {
+				fmt.Fprint(txt, "\nPaused")
+			} else if player.Done() {
+				fmt.Fprint(txt, "\nFinished")
 			}
-			txt := text.New(pixel.V(float64(-SideWindowHorizontalPadding/2+PixelScale+WidthSubForFullScreen), float64(PixelScale+PixelScale+SideWindowVerticalPadding+2*PixelScale+HeightSubForFullScreen)), atlas)
-			fmt.Fprint(txt, "Held")
 			txt.Draw(win, pixel.IM)
 		}
 
@@ -346,8 +555,49 @@ func run() {
 		win.Clear(color.RGBA{30, 30, 46, 255})
 		imd.Clear()
 	}
+
+	if game.Recording != nil {
+		if err := save_replay(game); err != nil {
+			fmt.Fprintln(os.Stderr, "saving replay:", err)
+		}
+	}
+	if *stats_flag != "" {
+		if err := views[0].stats.Stats().Save(*stats_flag); err != nil {
+			fmt.Fprintln(os.Stderr, "saving stats:", err)
+		}
+	}
 }
 
 func main() {
+	flag.Parse()
+	if *scores_flag {
+		print_scores()
+		return
+	}
+	if (*versus_flag || *connect_flag != "") && (*record_flag != "" || *replay_flag != "") {
+		fmt.Fprintln(os.Stderr, "versus matches can't be recorded or replayed")
+		os.Exit(2)
+	}
+	if (*bot_flag || *bot_command_flag != "") && (*connect_flag != "" || *record_flag != "" || *replay_flag != "") {
+		fmt.Fprintln(os.Stderr, "the bot can't play online, be recorded or play a replay")
+		os.Exit(2)
+	}
+	if (*bot_flag || *bot_command_flag != "") && (*pieces_flag != "tetrominoes" || *board_flag != "10x20") {
+		fmt.Fprintln(os.Stderr, "the bot only plays tetrominoes on a 10x20 board")
+		os.Exit(2)
+	}
+	if *finesse_restart_flag && (*versus_flag || *connect_flag != "" || *replay_flag != "" || *bot_flag || *bot_command_flag != "") {
+		fmt.Fprintln(os.Stderr, "finesse training is only for playing by yourself")
+		os.Exit(2)
+	}
+	if *finesse_restart_flag && *record_flag != "" {
+		// a replay is one game, and finesse training starts a new one after every fault
+		fmt.Fprintln(os.Stderr, "finesse training can't be recorded")
+		os.Exit(2)
+	}
+	if *bot_pps_flag <= 0 {
+		fmt.Fprintln(os.Stderr, "-bot-pps must be more than 0")
+		os.Exit(2)
+	}
 	pixelgl.Run(run)
 }
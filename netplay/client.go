@@ -0,0 +1,187 @@
+package netplay
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"tetris/engine"
+)
+
+// how long to wait for the server to answer a hello
+const helloTimeout = 5 * time.Second
+
+// Client is a connection to a tetris-server, for a player or a spectator
+type Client struct {
+	// the id the server gave us
+	ID int
+
+	// the room we are in
+	Room string
+
+	conn *conn
+
+	// every message from the server after the welcome, closed when the connection is
+	messages chan Message
+
+	// everything we send goes through send so a slow connection never holds up the game,
+	// err is the first thing that went wrong sending, after which nothing more is sent
+	mu     sync.Mutex
+	send   chan Message
+	err    error
+	closed bool
+
+	// the game being played online, and has a piece locked in it since its board was last sent
+	game   *engine.Game
+	locked bool
+}
+
+// connects to the server at addr and joins room, as a spectator if spectator is true
+func Dial(addr string, name string, room string, spectator bool) (*Client, error) {
+	nc, err := net.DialTimeout("tcp", addr, helloTimeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{Room: room, conn: newConn(nc), messages: make(chan Message, sendBuffer), send: make(chan Message, sendBuffer)}
+
+	if err := c.conn.write(Message{Type: TypeHello, Version: ProtocolVersion, Name: name, Room: room, Spectator: spectator}); err != nil {
+		nc.Close()
+		return nil, err
+	}
+	nc.SetReadDeadline(time.Now().Add(helloTimeout))
+	welcome, err := c.conn.read()
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	nc.SetReadDeadline(time.Time{})
+	if welcome.Type == TypeError {
+		nc.Close()
+		return nil, fmt.Errorf("server: %s", welcome.Error)
+	}
+	if welcome.Type != TypeWelcome {
+		nc.Close()
+		return nil, fmt.Errorf("expected %s from the server, got %s", TypeWelcome, welcome.Type)
+	}
+	c.ID = welcome.Player
+
+	go func() {
+		defer close(c.messages)
+		for {
+			m, err := c.conn.read()
+			if err != nil {
+				return
+			}
+			c.messages <- m
+		}
+	}()
+	go func() {
+		for m := range c.send {
+			if err := c.conn.write(m); err != nil {
+				c.fail(err)
+			}
+		}
+	}()
+	return c, nil
+}
+
+// queues a message to go to the server, returning why it can't if sending has already gone wrong
+func (c *Client) queue(m Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	if c.closed {
+		return errors.New("the connection is closed")
+	}
+	select {
+	case c.send <- m:
+		return nil
+	default:
+		c.failLocked(errors.New("too many messages waiting to go to the server"))
+		return c.err
+	}
+}
+
+// stops sending after something went wrong, closing the connection so Messages is closed too
+func (c *Client) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failLocked(err)
+}
+
+func (c *Client) failLocked(err error) {
+	if c.err == nil {
+		c.err = err
+		c.conn.c.Close()
+	}
+}
+
+// the first thing that went wrong sending to the server, nil if nothing has
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// the messages from the server, the channel is closed when the connection is lost
+func (c *Client) Messages() <-chan Message {
+	return c.messages
+}
+
+// tells the server whether we are ready to start a match
+func (c *Client) Ready(ready bool) error {
+	return c.queue(Message{Type: TypeReady, Ready: ready})
+}
+
+// sends what our board looks like to everyone else in the room
+func (c *Client) SendBoard(g *engine.Game) error {
+	return c.queue(Message{Type: TypeBoard, Width: g.PlayingBoard.Width, Board: EncodeBoard(g.ComposeBoard(false)), Score: g.Score, Lines: g.LinesCleared})
+}
+
+// sends lines of garbage to whoever we are attacking
+func (c *Client) SendAttack(lines int) error {
+	return c.queue(Message{Type: TypeAttack, Lines: lines})
+}
+
+// tells the server we have topped out
+func (c *Client) SendOver() error {
+	return c.queue(Message{Type: TypeOver})
+}
+
+// closes the connection, leaving the room
+func (c *Client) Close() error {
+	c.mu.Lock()
+	if !c.closed {
+		close(c.send)
+		c.closed = true
+	}
+	c.mu.Unlock()
+	return c.conn.c.Close()
+}
+
+// plays g online, whenever a piece locks the garbage it sends by the attack table is sent
+// once its own incoming garbage has been cancelled, and its board is sent by the next Update
+func (c *Client) Play(g *engine.Game, attack engine.AttackTable) {
+	c.game = g
+	c.locked = false
+	g.OnClear(func(e engine.ClearEvent) {
+		if lines := g.CancelGarbage(attack.Attack(e)); lines > 0 {
+			c.SendAttack(lines)
+		}
+		c.locked = true
+	})
+}
+
+// sends the board of the game being played if a piece has locked since it was last sent, this should be called after every step
+// so the board is sent once any garbage has risen under it, returns the first thing that went wrong sending to the server
+func (c *Client) Update() error {
+	if c.game != nil && c.locked {
+		c.locked = false
+		c.SendBoard(c.game)
+	}
+	return c.Err()
+}
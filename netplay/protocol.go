@@ -0,0 +1,197 @@
+// Package netplay lets games on different machines play versus matches through a tetris-server,
+// the server and the clients talk in JSON lines over TCP, one Message per line
+package netplay
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+
+	"tetris/engine"
+)
+
+// the version of the protocol, the server turns away clients with a different version
+const ProtocolVersion = 2
+
+// the port the server listens on when it isn't given one
+const DefaultPort = 7531
+
+// the types of message
+const (
+	// client to server, the first message on every connection, with Version, Name, Room and Spectator
+	TypeHello = "hello"
+
+	// server to client, the answer to hello when the client got in, with Player being its id
+	TypeWelcome = "welcome"
+
+	// server to client, something went wrong, with Error saying what, the connection is closed after it
+	TypeError = "error"
+
+	// server to client, who is in the room and who is ready, sent whenever that changes
+	TypeRoom = "room"
+
+	// client to server, the player is ready to start or isn't any more, with Ready
+	TypeReady = "ready"
+
+	// server to client, everyone is ready and the match starts in Seconds
+	TypeCountdown = "countdown"
+
+	// server to client, the match has started, everyone plays with Seed so they get the same pieces
+	TypeStart = "start"
+
+	// client to server and then on to everyone else in the room, what the board of Player looks like after a piece locked,
+	// Width pixels wide
+	TypeBoard = "board"
+
+	// client to server, the player sent Lines of garbage after cancelling out their own incoming garbage
+	TypeAttack = "attack"
+
+	// server to client, Lines of garbage with the hole at Hole across the board has been sent to the player by Player
+	TypeGarbage = "garbage"
+
+	// client to server and then on to everyone, Player has topped out
+	TypeOver = "over"
+
+	// server to client, the match is over and Winner won, 0 if nobody did
+	TypeResult = "result"
+)
+
+// Message is everything that is sent between the server and the clients, which fields are used depends on the Type
+type Message struct {
+	Type string `json:"type"`
+
+	Version   int    `json:"version,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Room      string `json:"room,omitempty"`
+	Spectator bool   `json:"spectator,omitempty"`
+	Error     string `json:"error,omitempty"`
+
+	// the id of the player the message is about, ids start at 1
+	Player  int          `json:"player,omitempty"`
+	Players []PlayerInfo `json:"players,omitempty"`
+	Ready   bool         `json:"ready,omitempty"`
+	Seconds int          `json:"seconds,omitempty"`
+	Seed    int64        `json:"seed,omitempty"`
+
+	Lines int `json:"lines,omitempty"`
+
+	// where the hole in garbage is, from 0 at the left wall up to 1 at the right,
+	// so it is in the same place whatever the width of the board it rises into
+	Hole float64 `json:"hole,omitempty"`
+
+	// the visible rows of the board from the bottom up, made by EncodeBoard, and how wide it is
+	Board []string `json:"board,omitempty"`
+	Width int      `json:"width,omitempty"`
+	Score int      `json:"score,omitempty"`
+
+	Winner int `json:"winner,omitempty"`
+}
+
+// the column the hole in garbage is in on a board width pixels wide
+func (m Message) HoleColumn(width int) int {
+	column := int(m.Hole * float64(width))
+	if column < 0 {
+		return 0
+	}
+	if column >= width {
+		return width - 1
+	}
+	return column
+}
+
+// PlayerInfo is a player in a room
+type PlayerInfo struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Ready bool   `json:"ready"`
+}
+
+// conn reads and writes messages on a connection
+type conn struct {
+	c       net.Conn
+	scanner *bufio.Scanner
+	enc     *json.Encoder
+}
+
+func newConn(c net.Conn) *conn {
+	scanner := bufio.NewScanner(c)
+	scanner.Buffer(make([]byte, 0, 4096), 1<<20)
+	return &conn{c: c, scanner: scanner, enc: json.NewEncoder(c)}
+}
+
+// reads the next message, blocking until one arrives
+func (c *conn) read() (Message, error) {
+	if !c.scanner.Scan() {
+		if err := c.scanner.Err(); err != nil {
+			return Message{}, err
+		}
+		return Message{}, fmt.Errorf("connection closed")
+	}
+	var m Message
+	if err := json.Unmarshal(c.scanner.Bytes(), &m); err != nil {
+		return Message{}, fmt.Errorf("reading message: %w", err)
+	}
+	return m, nil
+}
+
+// writes a message as one line
+func (c *conn) write(m Message) error {
+	return c.enc.Encode(m)
+}
+
+// turns the visible part of the board into one string per row from the bottom up, one character for each pixel,
+// '0' for empty, '#' for garbage and '0' plus the piece's place in its set for a piece
+func EncodeBoard(board engine.Board) []string {
+	rows := make([]string, board.Visible)
+	for i := range rows {
+		var row strings.Builder
+		for j := 0; j < board.Width; j++ {
+			switch pixel := board.Pixels[i][j]; {
+			case pixel == engine.GarbagePixel:
+				row.WriteByte('#')
+			case pixel > 0 && pixel < 'z'-'0':
+				row.WriteByte(byte('0' + pixel))
+			default:
+				row.WriteByte('0')
+			}
+		}
+		rows[i] = row.String()
+	}
+	return rows
+}
+
+// turns rows made by EncodeBoard back into a board width pixels wide, or as wide as the widest row if the width isn't known,
+// anything it doesn't know is left empty
+func DecodeBoard(rows []string, width int) engine.Board {
+	if width <= 0 {
+		for _, row := range rows {
+			if len(row) > width {
+				width = len(row)
+			}
+		}
+	}
+	if width < 1 {
+		width = 1
+	}
+	if width > engine.MaxBoardWidth {
+		width = engine.MaxBoardWidth
+	}
+	visible := len(rows)
+	if visible < 1 {
+		visible = 1
+	}
+	board := engine.NewBoard(width, visible)
+	for i, row := range rows {
+		for j := 0; j < len(row) && j < width; j++ {
+			switch c := row[j]; {
+			case c == '#':
+				board.Set(engine.Point{Row: i, Col: j}, engine.GarbagePixel)
+			case c > '0' && c <= 'z':
+				board.Set(engine.Point{Row: i, Col: j}, engine.Pixel(c-'0'))
+			}
+		}
+	}
+	return board
+}
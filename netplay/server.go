@@ -0,0 +1,371 @@
+package netplay
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// how many players can be in a room, there can be any number of spectators
+const MaxPlayers = 8
+
+// how many messages can be waiting to go out to a client before it is dropped for being too slow
+const sendBuffer = 256
+
+// Server keeps the rooms and passes messages between the players in them
+type Server struct {
+	// how long each second of the countdown before a match takes, this is only shorter in tests
+	CountdownStep time.Duration
+
+	// how many seconds the countdown before a match counts down from
+	CountdownSeconds int
+
+	// where to log who comes and goes, nil for no logging
+	Log *log.Logger
+
+	mu     sync.Mutex
+	rooms  map[string]*room
+	nextID int
+	rng    *rand.Rand
+}
+
+// a room that players wait in until everyone is ready, and then play a match in
+type room struct {
+	name string
+
+	// the players in the order they joined, this is the order garbage goes round in
+	players []*client
+
+	spectators []*client
+
+	// is a match being played
+	playing bool
+
+	// goes up every time a countdown starts or is called off, so an old countdown knows to stop
+	countdown int
+}
+
+// a connection to the server, from a player or a spectator
+type client struct {
+	id        int
+	name      string
+	spectator bool
+	ready     bool
+
+	// has the player topped out in the match being played
+	out bool
+
+	conn *conn
+	send chan Message
+}
+
+// returns a server with the usual 3 second countdown
+func NewServer() *Server {
+	return &Server{
+		CountdownStep:    time.Second,
+		CountdownSeconds: 3,
+		rooms:            map[string]*room{},
+		rng:              rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// accepts connections on l until it is closed, handling each one in its own goroutine
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go s.handle(c)
+	}
+}
+
+func (s *Server) logf(format string, args ...interface{}) {
+	if s.Log != nil {
+		s.Log.Printf(format, args...)
+	}
+}
+
+// talks to one client from its hello until it goes away
+func (s *Server) handle(nc net.Conn) {
+	defer nc.Close()
+	c := &client{conn: newConn(nc), send: make(chan Message, sendBuffer)}
+
+	hello, err := c.conn.read()
+	if err != nil {
+		return
+	}
+	if err := s.join(c, hello); err != nil {
+		c.conn.write(Message{Type: TypeError, Error: err.Error()})
+		return
+	}
+
+	// everything sent to the client goes through its send channel so a slow client never holds up the room
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for m := range c.send {
+			if c.conn.write(m) != nil {
+				nc.Close()
+			}
+		}
+	}()
+
+	for {
+		m, err := c.conn.read()
+		if err != nil {
+			break
+		}
+		s.receive(c, hello.Room, m)
+	}
+	s.leave(c, hello.Room)
+	close(c.send)
+	<-done
+}
+
+// puts a client that said hello into its room
+func (s *Server) join(c *client, hello Message) error {
+	if hello.Type != TypeHello {
+		return fmt.Errorf("expected %s, got %s", TypeHello, hello.Type)
+	}
+	if hello.Version != ProtocolVersion {
+		return fmt.Errorf("server speaks protocol version %d, client speaks %d", ProtocolVersion, hello.Version)
+	}
+	name := strings.TrimSpace(hello.Name)
+	if name == "" {
+		name = "Player"
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.rooms[hello.Room]
+	if r == nil {
+		r = &room{name: hello.Room}
+		s.rooms[hello.Room] = r
+	}
+	if !hello.Spectator && r.playing {
+		return fmt.Errorf("room %q is playing a match, try again once it's over", hello.Room)
+	}
+	if !hello.Spectator && len(r.players) >= MaxPlayers {
+		return fmt.Errorf("room %q is full", hello.Room)
+	}
+
+	s.nextID++
+	c.id = s.nextID
+	c.name = name
+	c.spectator = hello.Spectator
+	if c.spectator {
+		r.spectators = append(r.spectators, c)
+	} else {
+		r.players = append(r.players, c)
+	}
+	s.logf("%s (%d) joined room %q", c.name, c.id, r.name)
+
+	c.send <- Message{Type: TypeWelcome, Version: ProtocolVersion, Player: c.id, Room: r.name}
+	if r.playing {
+		c.send <- Message{Type: TypeStart, Players: r.info()}
+	}
+	r.broadcast(Message{Type: TypeRoom, Room: r.name, Players: r.info()}, nil)
+	return nil
+}
+
+// takes a client out of its room, a player leaving in the middle of a match has lost it
+func (s *Server) leave(c *client, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.rooms[name]
+	if r == nil {
+		return
+	}
+	s.logf("%s (%d) left room %q", c.name, c.id, r.name)
+	if !c.spectator && r.playing && !c.out {
+		s.playerOut(r, c)
+	}
+	r.players = remove(r.players, c)
+	r.spectators = remove(r.spectators, c)
+	if len(r.players) == 0 && len(r.spectators) == 0 {
+		delete(s.rooms, name)
+		return
+	}
+	s.checkReady(r)
+	r.broadcast(Message{Type: TypeRoom, Room: r.name, Players: r.info()}, nil)
+}
+
+// handles a message from a client that has joined a room
+func (s *Server) receive(c *client, name string, m Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.rooms[name]
+	if r == nil || c.spectator {
+		return
+	}
+
+	switch m.Type {
+	case TypeReady:
+		if r.playing {
+			return
+		}
+		c.ready = m.Ready
+		s.checkReady(r)
+		r.broadcast(Message{Type: TypeRoom, Room: r.name, Players: r.info()}, nil)
+
+	case TypeBoard:
+		if r.playing {
+			r.broadcast(Message{Type: TypeBoard, Player: c.id, Board: m.Board, Width: m.Width, Score: m.Score, Lines: m.Lines}, c)
+		}
+
+	case TypeAttack:
+		if !r.playing || c.out || m.Lines <= 0 {
+			return
+		}
+		if target := r.target(c); target != nil {
+			target.deliver(Message{Type: TypeGarbage, Player: c.id, Lines: m.Lines, Hole: s.rng.Float64()})
+		}
+
+	case TypeOver:
+		if r.playing && !c.out {
+			s.playerOut(r, c)
+		}
+	}
+}
+
+// starts the countdown if every player is ready, or calls it off if someone isn't any more
+func (s *Server) checkReady(r *room) {
+	if r.playing {
+		return
+	}
+	ready := len(r.players) >= 2
+	for _, p := range r.players {
+		ready = ready && p.ready
+	}
+	r.countdown++
+	if ready {
+		go s.countDown(r, r.countdown)
+	}
+}
+
+// counts down to the start of a match, stopping if the countdown is called off
+func (s *Server) countDown(r *room, countdown int) {
+	for n := s.CountdownSeconds; n > 0; n-- {
+		s.mu.Lock()
+		if r.countdown != countdown {
+			s.mu.Unlock()
+			return
+		}
+		r.broadcast(Message{Type: TypeCountdown, Seconds: n}, nil)
+		s.mu.Unlock()
+		time.Sleep(s.CountdownStep)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.countdown != countdown {
+		return
+	}
+	r.playing = true
+	for _, p := range r.players {
+		p.out = false
+	}
+	s.logf("room %q started a match with %d players", r.name, len(r.players))
+	r.broadcast(Message{Type: TypeStart, Seed: s.rng.Int63(), Players: r.info()}, nil)
+}
+
+// marks a player as having topped out, ending the match if there is only one player left
+func (s *Server) playerOut(r *room, c *client) {
+	c.out = true
+	r.broadcast(Message{Type: TypeOver, Player: c.id}, nil)
+
+	var left []*client
+	for _, p := range r.players {
+		if !p.out {
+			left = append(left, p)
+		}
+	}
+	if len(left) > 1 {
+		return
+	}
+	winner := 0
+	if len(left) == 1 {
+		winner = left[0].id
+	}
+	r.playing = false
+	for _, p := range r.players {
+		p.ready = false
+	}
+	s.logf("room %q finished a match, winner %d", r.name, winner)
+	r.broadcast(Message{Type: TypeResult, Winner: winner}, nil)
+	r.broadcast(Message{Type: TypeRoom, Room: r.name, Players: r.info()}, nil)
+}
+
+// the player that c sends garbage to, the next one along that is still in, nil if everyone else is out
+func (r *room) target(c *client) *client {
+	for i, p := range r.players {
+		if p != c {
+			continue
+		}
+		for j := 1; j < len(r.players); j++ {
+			t := r.players[(i+j)%len(r.players)]
+			if !t.out {
+				return t
+			}
+		}
+	}
+	return nil
+}
+
+// sends a message to everyone in the room apart from skip
+func (r *room) broadcast(m Message, skip *client) {
+	for _, clients := range [][]*client{r.players, r.spectators} {
+		for _, c := range clients {
+			if c != skip {
+				c.deliver(m)
+			}
+		}
+	}
+}
+
+// queues a message to go out to the client
+func (c *client) deliver(m Message) {
+	select {
+	case c.send <- m:
+	default:
+		// the client isn't keeping up, closing the connection makes it leave
+		c.conn.c.Close()
+	}
+}
+
+// the players in the room, in the order they joined
+func (r *room) info() []PlayerInfo {
+	info := []PlayerInfo{}
+	for _, p := range r.players {
+		info = append(info, PlayerInfo{ID: p.id, Name: p.name, Ready: p.ready})
+	}
+	return info
+}
+
+// the names of the rooms that have someone in them, in alphabetical order
+func (s *Server) RoomNames() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var names []string
+	for name := range s.rooms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// takes c out of clients
+func remove(clients []*client, c *client) []*client {
+	for i, other := range clients {
+		if other == c {
+			return append(clients[:i], clients[i+1:]...)
+		}
+	}
+	return clients
+}
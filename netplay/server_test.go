@@ -0,0 +1,192 @@
+package netplay
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"tetris/engine"
+)
+
+// how long to wait for a message before giving up on it
+const waitTime = 2 * time.Second
+
+// starts a server on a free port on localhost with a quick countdown, returning its address
+func startServer(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	s := NewServer()
+	s.CountdownStep = time.Millisecond
+	go s.Serve(l)
+	return l.Addr().String()
+}
+
+// connects to the server, failing the test if it can't
+func dial(t *testing.T, addr string, name string, spectator bool) *Client {
+	t.Helper()
+	c, err := Dial(addr, name, "test", spectator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// waits for the next message of type typ, skipping any others
+func expect(t *testing.T, c *Client, typ string) Message {
+	t.Helper()
+	timeout := time.After(waitTime)
+	for {
+		select {
+		case m, ok := <-c.Messages():
+			if !ok {
+				t.Fatalf("connection closed waiting for %s", typ)
+			}
+			if m.Type == typ {
+				return m
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for %s", typ)
+		}
+	}
+}
+
+func TestVersionMismatchIsTurnedAway(t *testing.T) {
+	addr := startServer(t)
+	nc, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+	c := newConn(nc)
+	c.write(Message{Type: TypeHello, Version: ProtocolVersion + 1, Name: "old", Room: "test"})
+	m, err := c.read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Type != TypeError {
+		t.Fatalf("got %s for a hello with the wrong version, expected %s", m.Type, TypeError)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	addr := startServer(t)
+	alice := dial(t, addr, "alice", false)
+	bob := dial(t, addr, "bob", false)
+	watcher := dial(t, addr, "watcher", true)
+
+	// the match only starts once both players are ready
+	alice.Ready(true)
+	room := expect(t, bob, TypeRoom)
+	for len(room.Players) < 2 || !room.Players[0].Ready {
+		room = expect(t, bob, TypeRoom)
+	}
+	bob.Ready(true)
+	expect(t, alice, TypeCountdown)
+	start := expect(t, alice, TypeStart)
+	if other := expect(t, bob, TypeStart); other.Seed != start.Seed {
+		t.Fatalf("players were given different seeds, %d and %d", start.Seed, other.Seed)
+	}
+	expect(t, watcher, TypeStart)
+
+	// garbage goes to the opponent, and boards go to everyone else
+	alice.SendAttack(4)
+	garbage := expect(t, bob, TypeGarbage)
+	if garbage.Lines != 4 || garbage.Player != alice.ID {
+		t.Fatalf("bob got %d lines of garbage from %d, expected 4 from alice (%d)", garbage.Lines, garbage.Player, alice.ID)
+	}
+	if garbage.Hole < 0 || garbage.Hole >= 1 {
+		t.Fatalf("the hole is at %v across the board", garbage.Hole)
+	}
+	alice.queue(Message{Type: TypeBoard, Board: []string{"11111110"}, Width: 8, Score: 100})
+	if board := expect(t, watcher, TypeBoard); board.Player != alice.ID || board.Score != 100 || board.Width != 8 {
+		t.Fatalf("spectator got a board %d wide from %d scoring %d, expected alice's (%d) 8 wide scoring 100", board.Width, board.Player, board.Score, alice.ID)
+	}
+
+	// topping out ends the match with the other player winning
+	bob.SendOver()
+	expect(t, watcher, TypeOver)
+	if result := expect(t, watcher, TypeResult); result.Winner != alice.ID {
+		t.Fatalf("winner was %d, expected alice (%d)", result.Winner, alice.ID)
+	}
+}
+
+func TestLeavingDuringMatchLoses(t *testing.T) {
+	addr := startServer(t)
+	alice := dial(t, addr, "alice", false)
+	bob := dial(t, addr, "bob", false)
+	alice.Ready(true)
+	bob.Ready(true)
+	expect(t, alice, TypeStart)
+
+	bob.Close()
+	if result := expect(t, alice, TypeResult); result.Winner != alice.ID {
+		t.Fatalf("winner was %d after bob left, expected alice (%d)", result.Winner, alice.ID)
+	}
+}
+
+func TestBoardRoundTrip(t *testing.T) {
+	rows := []string{"1234567890", "0000000009", "#####0####"}
+	board := DecodeBoard(rows, 10)
+	got := EncodeBoard(board)
+	for i, row := range rows {
+		if got[i] != row {
+			t.Fatalf("row %d came back as %q, expected %q", i, got[i], row)
+		}
+	}
+}
+
+func TestHoleColumn(t *testing.T) {
+	for _, c := range []struct {
+		hole          float64
+		width, column int
+	}{
+		{0, 10, 0},
+		{0.55, 10, 5},
+		{0.55, 4, 2},
+		{0.999, 16, 15},
+		{1, 10, 9},
+		{-0.5, 10, 0},
+	} {
+		if got := (Message{Hole: c.hole}).HoleColumn(c.width); got != c.column {
+			t.Fatalf("a hole at %v on a board %d wide is in column %d, expected %d", c.hole, c.width, got, c.column)
+		}
+	}
+}
+
+// a board keeps the width it was sent with even when its right hand columns are empty
+func TestBoardWidth(t *testing.T) {
+	if board := DecodeBoard([]string{"110000"}, 6); board.Width != 6 {
+		t.Fatalf("a board 6 wide came back %d wide", board.Width)
+	}
+	if board := DecodeBoard([]string{"1100", "11"}, 0); board.Width != 4 {
+		t.Fatalf("a board of unknown width came back %d wide, expected the widest row", board.Width)
+	}
+}
+
+// the board is sent after the garbage a lock let in has risen under it
+func TestBoardSentAfterGarbage(t *testing.T) {
+	addr := startServer(t)
+	alice := dial(t, addr, "alice", false)
+	bob := dial(t, addr, "bob", false)
+	alice.Ready(true)
+	bob.Ready(true)
+	expect(t, alice, TypeStart)
+
+	g := engine.NewGame(1, engine.NewBagRandomizer(1, 1))
+	g.SetNextTetroFromBag()
+	alice.Play(&g, engine.AttackTable{})
+	g.ReceiveGarbage(2, 3)
+	g.HardDrop()
+	if err := alice.Update(); err != nil {
+		t.Fatal(err)
+	}
+	board := expect(t, bob, TypeBoard)
+	if len(board.Board) < 2 || board.Board[0][3] != '0' || board.Board[0][0] != '#' || board.Board[1][0] != '#' {
+		t.Fatalf("the board was sent as %q, expected 2 rows of garbage at the bottom with a hole in column 3", board.Board)
+	}
+}
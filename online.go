@@ -0,0 +1,256 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"os"
+	"time"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+	"github.com/faiface/pixel/pixelgl"
+	"github.com/faiface/pixel/text"
+
+	"tetris/engine"
+	"tetris/netplay"
+)
+
+// everything we know about the room we're in on the server
+type onlineRoom struct {
+	client *netplay.Client
+
+	// the players in the room, in the order the server lists them
+	players []netplay.PlayerInfo
+
+	// have we said we're ready for the next match
+	ready bool
+
+	// the seconds left in the countdown to the match, 0 when there isn't one
+	countdown int
+
+	// is a match being played
+	playing bool
+
+	// our game in the match, nil if we're spectating or no match has started yet
+	game *engine.Game
+
+	// have we told the server we topped out
+	sent_over bool
+
+	// the boards in the match by player id, and the order they are drawn in
+	views map[int]*boardView
+	order []int
+
+	// the players that have topped out in the match
+	out map[int]bool
+
+	// who won the last match, 0 for nobody
+	winner int
+
+	// set when the connection to the server is lost
+	lost bool
+}
+
+// the name of the player with the id
+func (r *onlineRoom) name(id int) string {
+	for _, p := range r.players {
+		if p.ID == id {
+			return p.Name
+		}
+	}
+	return fmt.Sprintf("Player %d", id)
+}
+
+// reports something going wrong sending to the server, after which the connection is as good as lost
+func (r *onlineRoom) sending(err error) {
+	if err != nil && !r.lost {
+		fmt.Fprintln(os.Stderr, "sending to the server:", err)
+		r.lost = true
+	}
+}
+
+// sets up the boards for a match that has started, our own game first if we're playing and then everyone else
+func (r *onlineRoom) start(m netplay.Message, cfg Config) error {
+	r.playing = true
+	r.countdown = 0
+	r.winner = 0
+	r.sent_over = false
+	r.game = nil
+	r.views = map[int]*boardView{}
+	r.order = nil
+	r.out = map[int]bool{}
+
+	if m.Seed != 0 && !*spectate_flag {
+		game, err := new_player_game(cfg, m.Seed)
+		if err != nil {
+			return err
+		}
+		game.SetNextTetroFromBag()
+		r.client.Play(game, cfg.Attack)
+		r.game = game
+		r.views[r.client.ID] = new_board_view(game, cfg.Attack)
+		r.order = append(r.order, r.client.ID)
+	}
+	for _, p := range m.Players {
+		if p.ID == r.client.ID {
+			continue
+		}
+		game := engine.NewGame(0, nil)
+		r.views[p.ID] = &boardView{game: &game, remote: true}
+		r.order = append(r.order, p.ID)
+	}
+	return nil
+}
+
+// handles a message from the server
+func (r *onlineRoom) receive(m netplay.Message, cfg Config) error {
+	switch m.Type {
+	case netplay.TypeRoom:
+		r.players = m.Players
+
+	case netplay.TypeCountdown:
+		r.countdown = m.Seconds
+
+	case netplay.TypeStart:
+		return r.start(m, cfg)
+
+	case netplay.TypeBoard:
+		if v := r.views[m.Player]; v != nil && v.remote {
+			v.game.PlayingBoard = netplay.DecodeBoard(m.Board, m.Width)
+			v.game.Score = m.Score
+			v.game.LinesCleared = m.Lines
+		}
+
+	case netplay.TypeGarbage:
+		if r.game != nil {
+			r.game.ReceiveGarbage(m.Lines, m.HoleColumn(r.game.PlayingBoard.Width))
+		}
+
+	case netplay.TypeOver:
+		r.out[m.Player] = true
+
+	case netplay.TypeResult:
+		r.playing = false
+		r.ready = false
+		r.winner = m.Winner
+	}
+	return nil
+}
+
+// plays online on the server given with -connect, or watches a room there with -spectate
+func run_online(cfg Config, keys keyBindings) {
+	client, err := netplay.Dial(*connect_flag, *name_flag, *room_flag, *spectate_flag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "connecting to", *connect_flag+":", err)
+		os.Exit(1)
+	}
+	defer client.Close()
+
+	win, atlas := open_window()
+	defer win.Destroy()
+
+	var (
+		// imdraw struct to draw shapes on the screen
+		imd = imdraw.New(nil)
+
+		// last frame is when the previous frame started, used to tell the game how much time has passed
+		last_frame = time.Now()
+
+		room = &onlineRoom{client: client}
+	)
+
+	for !win.Closed() {
+		imd.Reset()
+
+		dt := time.Since(last_frame)
+		last_frame = time.Now()
+
+		// handling everything the server has sent since the last frame
+	messages:
+		for {
+			select {
+			case m, ok := <-client.Messages():
+				if !ok {
+					room.lost = true
+					break messages
+				}
+				if err := room.receive(m, cfg); err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					return
+				}
+			default:
+				break messages
+			}
+		}
+
+		// escape leaves, enter says we're ready between matches, and during a match the keys play our game
+		if win.JustPressed(pixelgl.KeyEscape) {
+			break
+		}
+		if room.playing && room.game != nil {
+			room.game.Step(dt, keys.input(win))
+			room.sending(client.Update())
+			if room.game.GameOver && !room.sent_over {
+				room.sending(client.SendOver())
+				room.sent_over = true
+			}
+		} else if !room.playing && !*spectate_flag && !room.lost && win.JustPressed(pixelgl.KeyEnter) {
+			room.ready = !room.ready
+			room.sending(client.Ready(room.ready))
+		}
+
+		// showing every board in the match, with who it belongs to over the top of it
+		for i, id := range room.order {
+			offset_x, offset_y := board_offset(win, i, len(room.order))
+			room.views[id].draw(win, imd, atlas, offset_x, offset_y)
+
+			txt := text.New(pixel.V(PixelScale+Padding+offset_x, BoardHeight+Padding*2+offset_y), atlas)
+			fmt.Fprint(txt, room.name(id))
+			txt.Draw(win, pixel.IM)
+
+			txt = text.New(board_top_left(offset_x, offset_y), atlas)
+			switch {
+			case room.winner == id:
+				fmt.Fprintln(txt, "WINNER")
+			case room.out[id]:
+				// only our own game knows how it was lost
+				fmt.Fprintln(txt, "OUT")
+				fmt.Fprintln(txt, room.views[id].game.GameOverReason)
+			}
+			txt.Draw(win, pixel.IM)
+		}
+
+		// showing the room, who is ready and the countdown in the top left of the window
+		txt := text.New(pixel.V(PixelScale, win.Bounds().H()-PixelScale*2), atlas)
+		fmt.Fprintf(txt, "Room %s on %s\n\n", client.Room, *connect_flag)
+		for _, p := range room.players {
+			ready := ""
+			if p.Ready {
+				ready = " - ready"
+			}
+			fmt.Fprintf(txt, "%s%s\n", p.Name, ready)
+		}
+		fmt.Fprintln(txt)
+		switch {
+		case room.lost:
+			fmt.Fprintln(txt, "Lost the connection to the server, press escape to quit")
+		case room.countdown > 0:
+			fmt.Fprintf(txt, "Starting in %d\n", room.countdown)
+		case room.playing:
+			// the boards say everything there is to say during a match
+		case *spectate_flag:
+			fmt.Fprintln(txt, "Spectating, waiting for the next match")
+		case room.ready:
+			fmt.Fprintln(txt, "Waiting for everyone to be ready, press enter if you aren't")
+		default:
+			fmt.Fprintln(txt, "Press enter when you're ready")
+		}
+		txt.Draw(win, pixel.IM)
+
+		// clearing the screen for the next frame
+		imd.Draw(win)
+		win.Update()
+		win.Clear(color.RGBA{30, 30, 46, 255})
+		imd.Clear()
+	}
+}
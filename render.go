@@ -0,0 +1,267 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+	"github.com/faiface/pixel/pixelgl"
+	"github.com/faiface/pixel/text"
+
+	"tetris/engine"
+	"tetris/scores"
+	"tetris/stats"
+)
+
+// how far apart the boards are when there's more than one, wide enough for the held piece on the left
+// and the next pieces and score on the right
+const BoardSlotWidth = SideWindowHorizontalPadding*3/2 + SidePanelTextOffset + PixelScale*5
+
+// a game drawn on the screen, along with the popups it has shown
+type boardView struct {
+	game *engine.Game
+
+	// is the game being played on another machine, only its stack and score are known
+	remote bool
+
+	// the text shown when a piece scores something special, like "T-SPIN DOUBLE" or "B2B"
+	popup []string
+
+	// when the popup was shown, it disappears after PopupMillis
+	popup_time time.Time
+
+	// the stats of the game, nil for a remote game
+	stats *stats.Collector
+
+	// is the stats panel shown next to the score
+	show_stats bool
+}
+
+// makes the view for a game, collecting its stats with the attack table
+// and showing a popup whenever a piece locks and clears something worth mentioning
+func new_board_view(game *engine.Game, attack engine.AttackTable) *boardView {
+	v := &boardView{game: game, stats: stats.NewCollector(game, attack)}
+	game.OnClear(func(e engine.ClearEvent) {
+		labels := e.Labels()
+		if e.FinesseFaults > 0 {
+			labels = append(labels, fmt.Sprintf("FINESSE +%d", e.FinesseFaults))
+		}
+		if len(labels) > 0 {
+			v.popup = labels
+			v.popup_time = time.Now()
+		}
+	})
+	return v
+}
+
+// writes the stats of a game into w, one per line
+func write_stats(w io.Writer, s stats.Stats) {
+	holes := 0
+	if len(s.Holes) > 0 {
+		holes = s.Holes[len(s.Holes)-1].Holes
+	}
+	fmt.Fprintln(w, "Stats")
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Pieces     %d\n", s.Pieces)
+	fmt.Fprintf(w, "PPS        %.2f\n", s.PPS)
+	fmt.Fprintf(w, "KPP        %.2f\n", s.KPP)
+	fmt.Fprintf(w, "APM        %.1f\n", s.APM)
+	fmt.Fprintf(w, "Attack     %d\n", s.Attack)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Singles    %d\n", s.Singles)
+	fmt.Fprintf(w, "Doubles    %d\n", s.Doubles)
+	fmt.Fprintf(w, "Triples    %d\n", s.Triples)
+	fmt.Fprintf(w, "Tetrises   %d\n", s.Tetrises)
+	fmt.Fprintf(w, "T-spins    %d/%d/%d\n", s.TSpinSingles, s.TSpinDoubles, s.TSpinTriples)
+	fmt.Fprintf(w, "Minis      %d\n", s.TSpinMinis)
+	fmt.Fprintf(w, "Perfect    %d\n", s.PerfectClears)
+	fmt.Fprintf(w, "Max combo  %d\n", s.MaxCombo)
+	fmt.Fprintf(w, "Holes      %d\n", holes)
+}
+
+// where board i of n is drawn, so that all the boards sit side by side in the middle of the window,
+// a single board is right in the middle
+func board_offset(win *pixelgl.Window, i int, n int) (offset_x float64, offset_y float64) {
+	offset_x = (win.Bounds().W() / 2) - (BoardWidth / 2) + (float64(i)-float64(n-1)/2)*BoardSlotWidth
+	offset_y = (win.Bounds().H() / 2) - (BoardHeight / 2)
+	return offset_x, offset_y
+}
+
+// where the text shown over a board starts, like the game over screen
+func board_top_left(offset_x float64, offset_y float64) pixel.Vec {
+	return pixel.V(PixelScale+Padding+offset_x, BoardHeight-PixelScale+offset_y)
+}
+
+// how many screen pixels wide and tall a pixel of the board is, the biggest that fits the board in the space
+// a standard board takes up
+func board_scale(board *engine.Board) int {
+	scale := BoardWidth / board.Width
+	if tall := BoardHeight / board.Visible; tall < scale {
+		scale = tall
+	}
+	return scale
+}
+
+// draws the board with the game on it, the held and next pieces, the score and the popups,
+// with the bottom left of the board offset by offset_x and offset_y
+func (v *boardView) draw(win *pixelgl.Window, imd *imdraw.IMDraw, atlas *text.Atlas, offset_x float64, offset_y float64) {
+	// the stack with the ghost tetro and the current tetro on top, there's no current tetro during the line clear delay
+	board := v.game.ComposeBoard(true)
+
+	// boards that aren't the standard size are scaled to fit in the same space, with the gaps between pixels scaled with them
+	scale := board_scale(&board)
+	gap := (Padding - Padding/2 + BorderWidth*2 - BorderWidth/2) * scale / PixelScale
+
+	// setting all the pixels, the ghost still shows above the visible part of the board
+	for i := 0; i < board.Height; i++ {
+		for j := 0; j < board.Width; j++ {
+			if i < board.Visible || board.Pixels[i][j] == engine.GhostPixel {
+				imd.Color = pixel.ToRGBA(v.game.Pieces.Color(board.Pixels[i][j]))
+			} else {
+				imd.Color = pixel.ToRGBA(color.Transparent)
+			}
+			imd.Push(pixel.V(float64(scale*j+Padding+(BorderWidth*2)+int(offset_x)), float64(scale*i+Padding+(BorderWidth*2)+int(offset_y))))
+
+			imd.Push(pixel.V(float64(scale*j+scale-gap+Padding+(BorderWidth*2)+int(offset_x)), float64(scale*i+scale-gap+Padding+(BorderWidth*2)+int(offset_y))))
+
+			imd.Rectangle(0)
+		}
+	}
+
+	// showing the border of the board
+	imd.Color = color.RGBA{100, 100, 100, 100}
+	imd.Push(pixel.V(Padding+offset_x, Padding+offset_y))
+	imd.Push(pixel.V(float64(board.Width*scale+Padding+BorderWidth)+offset_x, float64(board.Visible*scale+Padding+BorderWidth)+offset_y))
+	imd.Rectangle(BorderWidth)
+
+	// showing the next pieces, stacked under each other with a pixel between the tallest pieces of the set,
+	// the pieces coming next on a board from another machine aren't known
+	txt := text.New(pixel.V(float64(SideWindowHorizontalPadding+PixelScale+offset_x), float64(PixelScale+PixelScale+SideWindowVerticalPadding+2*PixelScale+offset_y)), atlas)
+	if !v.remote {
+		spacing := v.game.Pieces.MaxHeight() + 1
+		for k, next := range v.game.PeekNext(v.game.QueueLength) {
+			shape, _, _ := v.game.Pieces.Preview(next)
+			for i := 0; i < len(shape); i++ {
+				shape[i].Row -= spacing * k
+			}
+			for i := 0; i < len(shape); i++ {
+				imd.Color = pixel.ToRGBA(v.game.Pieces.Color(engine.Pixel(next)))
+				imd.Push(pixel.V(float64(SideWindowHorizontalPadding+shape[i].Col*PixelScale+PixelScale+Padding+int(offset_x)), float64(SideWindowVerticalPadding+PixelScale+shape[i].Row*PixelScale+Padding+int(offset_y))))
+				imd.Push(pixel.V(float64(PixelScale+PixelScale+SideWindowHorizontalPadding+shape[i].Col*PixelScale+int(offset_x)), float64(PixelScale+PixelScale+SideWindowVerticalPadding+shape[i].Row*PixelScale+int(offset_y))))
+				imd.Rectangle(0)
+			}
+		}
+		// displaying next for the next piece
+		fmt.Fprint(txt, "Next")
+		txt.Draw(win, pixel.IM)
+	}
+
+	// displaying the score text, to the right of the next pieces so they don't overlap
+	txt = text.New(pixel.V(float64(SideWindowHorizontalPadding+SidePanelTextOffset+offset_x), float64(PixelScale+PixelScale+SideWindowVerticalPadding+2*PixelScale)), atlas)
+	fmt.Fprint(txt, "Score")
+	txt.Draw(win, pixel.IM)
+	txt = text.New(pixel.V(float64(SideWindowHorizontalPadding+SidePanelTextOffset+offset_x), float64(PixelScale+PixelScale+SideWindowVerticalPadding+2*PixelScale-PixelScale)), atlas)
+	fmt.Fprint(txt, strconv.Itoa(v.game.Score))
+	txt.Draw(win, pixel.IM)
+
+	// displaying the level text
+	txt = text.New(pixel.V(float64(SideWindowHorizontalPadding+SidePanelTextOffset+offset_x), float64(PixelScale+PixelScale+SideWindowVerticalPadding+2*PixelScale-PixelScale-PixelScale)), atlas)
+	fmt.Fprint(txt, "Level")
+	txt.Draw(win, pixel.IM)
+	txt = text.New(pixel.V(float64(SideWindowHorizontalPadding+SidePanelTextOffset+offset_x), float64(PixelScale+PixelScale+SideWindowVerticalPadding+2*PixelScale-PixelScale-PixelScale-PixelScale)), atlas)
+	fmt.Fprint(txt, strconv.Itoa(v.game.Level))
+	txt.Draw(win, pixel.IM)
+
+	// displaying how many lines are left until the next level
+	txt = text.New(pixel.V(float64(SideWindowHorizontalPadding+SidePanelTextOffset+offset_x), float64(PixelScale+PixelScale+SideWindowVerticalPadding+2*PixelScale-4*PixelScale)), atlas)
+	fmt.Fprint(txt, "Goal")
+	txt.Draw(win, pixel.IM)
+	txt = text.New(pixel.V(float64(SideWindowHorizontalPadding+SidePanelTextOffset+offset_x), float64(PixelScale+PixelScale+SideWindowVerticalPadding+2*PixelScale-5*PixelScale)), atlas)
+	fmt.Fprint(txt, strconv.Itoa(v.game.LinesToNextLevel()))
+	txt.Draw(win, pixel.IM)
+
+	// showing the timer, lines left, pieces per second and splits of a sprint, under the goal
+	if sprint, ok := v.game.Mode.(*engine.Sprint); ok {
+		txt = text.New(pixel.V(float64(SideWindowHorizontalPadding+SidePanelTextOffset+offset_x), float64(PixelScale+PixelScale+SideWindowVerticalPadding+2*PixelScale-7*PixelScale)), atlas)
+		fmt.Fprintln(txt, scores.FormatTime(v.game.PlayTime))
+		fmt.Fprintf(txt, "%d lines left\n", sprint.LinesLeft(v.game))
+		fmt.Fprintf(txt, "%.2f PPS\n", v.game.PiecesPerSecond())
+		for i, split := range sprint.Splits {
+			fmt.Fprintf(txt, "%3d  %s\n", (i+1)*sprint.SplitLines, scores.FormatTime(split))
+		}
+		txt.Draw(win, pixel.IM)
+	}
+
+	// showing the timer and how much garbage is left in a dig, under the goal
+	if _, ok := v.game.Mode.(*engine.Dig); ok {
+		txt = text.New(pixel.V(float64(SideWindowHorizontalPadding+SidePanelTextOffset+offset_x), float64(PixelScale+PixelScale+SideWindowVerticalPadding+2*PixelScale-7*PixelScale)), atlas)
+		fmt.Fprintln(txt, scores.FormatTime(v.game.PlayTime))
+		fmt.Fprintf(txt, "%d garbage left\n", v.game.GarbageLeft())
+		txt.Draw(win, pixel.IM)
+	}
+
+	// showing how long is left of an ultra, under the goal
+	if ultra, ok := v.game.Mode.(*engine.Ultra); ok {
+		txt = text.New(pixel.V(float64(SideWindowHorizontalPadding+SidePanelTextOffset+offset_x), float64(PixelScale+PixelScale+SideWindowVerticalPadding+2*PixelScale-7*PixelScale)), atlas)
+		fmt.Fprint(txt, "Time left")
+		txt.Draw(win, pixel.IM)
+		txt = text.New(pixel.V(float64(SideWindowHorizontalPadding+SidePanelTextOffset+offset_x), float64(PixelScale+PixelScale+SideWindowVerticalPadding+2*PixelScale-8*PixelScale)), atlas)
+		fmt.Fprint(txt, scores.FormatTime(ultra.TimeLeft(v.game)))
+		txt.Draw(win, pixel.IM)
+	}
+
+	// showing the held piece
+	if v.game.HeldPiece != 0 {
+		shape, _, _ := v.game.Pieces.Preview(engine.Tetro(v.game.HeldPiece))
+		for i := 0; i < len(shape); i++ {
+			imd.Color = pixel.ToRGBA(v.game.Pieces.Color(engine.Pixel(v.game.HeldPiece)))
+			imd.Push(pixel.V(float64(-(SideWindowHorizontalPadding/2)+(shape[i].Col*PixelScale)+(PixelScale+Padding)+int(offset_x)), float64((SideWindowVerticalPadding)+(PixelScale+Padding)+(shape[i].Row*PixelScale)+int(offset_y))))
+			imd.Push(pixel.V(float64(PixelScale+PixelScale-SideWindowHorizontalPadding/2+shape[i].Col*PixelScale+int(offset_x)), float64(PixelScale+PixelScale+SideWindowVerticalPadding+shape[i].Row*PixelScale+int(offset_y))))
+			imd.Rectangle(0)
+		}
+		txt := text.New(pixel.V(float64(-SideWindowHorizontalPadding/2+PixelScale+offset_x), float64(PixelScale+PixelScale+SideWindowVerticalPadding+2*PixelScale+offset_y)), atlas)
+		fmt.Fprint(txt, "Held")
+		txt.Draw(win, pixel.IM)
+	}
+
+	// showing what the last clear scored, under the held piece
+	if time.Since(v.popup_time) < time.Millisecond*time.Duration(PopupMillis) {
+		txt := text.New(pixel.V(float64(-SideWindowHorizontalPadding/2+PixelScale+offset_x), float64(SideWindowVerticalPadding-PixelScale+offset_y)), atlas)
+		for _, line := range v.popup {
+			fmt.Fprintln(txt, line)
+		}
+		txt.Draw(win, pixel.IM)
+	}
+
+	// showing how many keys have been wasted and how many pieces were placed with as few keys as they could be,
+	// under the popups
+	if !v.remote {
+		txt := text.New(pixel.V(float64(-SideWindowHorizontalPadding/2+PixelScale+offset_x), float64(SideWindowVerticalPadding-7*PixelScale+offset_y)), atlas)
+		fmt.Fprintln(txt, "Finesse")
+		fmt.Fprintf(txt, "%.1f%%\n", v.game.FinessePercent())
+		fmt.Fprintf(txt, "%d faults\n", v.game.FinesseFaults)
+		txt.Draw(win, pixel.IM)
+	}
+
+	// showing the stats panel to the right of the score
+	if v.show_stats && v.stats != nil {
+		txt := text.New(pixel.V(float64(SideWindowHorizontalPadding+SidePanelTextOffset+6*PixelScale+offset_x), float64(PixelScale+PixelScale+SideWindowVerticalPadding+2*PixelScale+offset_y)), atlas)
+		write_stats(txt, v.stats.Stats())
+		txt.Draw(win, pixel.IM)
+	}
+
+	// showing the garbage waiting to rise into the board as a red bar down the left of it
+	if incoming := v.game.IncomingLines(); incoming > 0 {
+		if incoming > board.Visible {
+			incoming = board.Visible
+		}
+		imd.Color = color.RGBA{243, 139, 168, 255}
+		imd.Push(pixel.V(Padding-BorderWidth-PixelScale/3+offset_x, Padding+BorderWidth+offset_y))
+		imd.Push(pixel.V(Padding-BorderWidth+offset_x, float64(Padding+BorderWidth+incoming*scale)+offset_y))
+		imd.Rectangle(0)
+	}
+}
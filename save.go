@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"tetris/engine"
+	"tetris/scores"
+	"tetris/stats"
+)
+
+// saveFile is what goes in the save file, the game along with the stats collected for it so far
+type saveFile struct {
+	Game  *engine.SavedGame `json:"game"`
+	Stats stats.Stats       `json:"stats"`
+}
+
+// where a game saved to carry on later is kept, next to the high score table
+func save_path() (string, error) {
+	dir, err := scores.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "save.json"), nil
+}
+
+// saves the game and its stats to carry on later, replacing any game that was saved before
+func save_game(game *engine.Game, collected stats.Stats) error {
+	path, err := save_path()
+	if err != nil {
+		return err
+	}
+	s, err := game.Save()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(saveFile{Game: s, Stats: collected}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	// writing to a temporary file first so a crash can't leave half a save behind
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// is there a saved game to carry on
+func has_saved_game() bool {
+	path, err := save_path()
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return !errors.Is(err, fs.ErrNotExist)
+}
+
+// loads the saved game and its stats and removes the save, so the same game can't be carried on twice
+func load_saved_game() (*engine.Game, stats.Stats, error) {
+	path, err := save_path()
+	if err != nil {
+		return nil, stats.Stats{}, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, stats.Stats{}, err
+	}
+	var s saveFile
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, stats.Stats{}, fmt.Errorf("reading save: %w", err)
+	}
+	if s.Game == nil {
+		return nil, stats.Stats{}, errors.New("the save has no game in it")
+	}
+	game, err := s.Game.Resume()
+	if err != nil {
+		return nil, stats.Stats{}, err
+	}
+	if err := remove_saved_game(); err != nil {
+		return nil, stats.Stats{}, err
+	}
+	return &game, s.Stats, nil
+}
+
+// throws away the saved game, if there is one
+func remove_saved_game() error {
+	path, err := save_path()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
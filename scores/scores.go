@@ -0,0 +1,241 @@
+// Package scores keeps the local high score table, with the best games for each game mode and ruleset
+package scores
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// how many entries are kept for each game mode and ruleset
+const MaxEntries = 10
+
+// Entry is one finished game in the high score table
+type Entry struct {
+	Name  string        `json:"name"`
+	Score int           `json:"score"`
+	Level int           `json:"level"`
+	Lines int           `json:"lines"`
+	Time  time.Duration `json:"time"`
+	Date  time.Time     `json:"date"`
+
+	// the board size and piece set the game was played with, empty for entries saved before they were kept
+	Board  string `json:"board,omitempty"`
+	Pieces string `json:"pieces,omitempty"`
+}
+
+// Ruleset is everything about how a game was played that changes what score it can get,
+// a game is only ranked against games played to the same ruleset
+type Ruleset struct {
+	// the name of the game mode
+	Mode string
+
+	// the board size as WIDTHxHEIGHT, like "10x20"
+	Board string
+
+	// the name of the piece set
+	Pieces string
+
+	// the level the game started at
+	StartLevel int
+
+	// the gravity curve and the randomizer, by the names the game gives them
+	Gravity    string
+	Randomizer string
+}
+
+// the ruleset of a game played with nothing changed from the defaults apart from the mode
+var Standard = Ruleset{
+	Board:      "10x20",
+	Pieces:     "tetrominoes",
+	StartLevel: 1,
+	Gravity:    "guideline",
+	Randomizer: "7bag",
+}
+
+// the name the ruleset's entries are kept under, which is just the name of the mode for a standard game,
+// with anything that isn't standard after it, like "sprint 12x24 pentominoes"
+func (r Ruleset) Key() string {
+	parts := []string{r.Mode}
+	if r.Board != Standard.Board {
+		parts = append(parts, r.Board)
+	}
+	if r.Pieces != Standard.Pieces {
+		parts = append(parts, r.Pieces)
+	}
+	if r.StartLevel != Standard.StartLevel {
+		parts = append(parts, "level "+strconv.Itoa(r.StartLevel))
+	}
+	if r.Gravity != Standard.Gravity {
+		parts = append(parts, r.Gravity+" gravity")
+	}
+	if r.Randomizer != Standard.Randomizer {
+		parts = append(parts, r.Randomizer)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Order is how the entries for a game mode are ranked
+type Order int
+
+const (
+	// the highest score is best, for modes that go on until the game is lost
+	ByScore Order = iota
+
+	// the fastest time is best, for modes that are a race to a goal
+	ByTime
+)
+
+// is entry a better than entry b
+func (o Order) better(a, b Entry) bool {
+	if o == ByTime {
+		return a.Time < b.Time
+	}
+	return a.Score > b.Score
+}
+
+// Table is the high score table, the best entries for each ruleset by its key, best first
+type Table struct {
+	Modes map[string][]Entry `json:"modes"`
+}
+
+// the directory the game keeps its data in, this is $XDG_DATA_HOME/tetris, or ~/.local/share/tetris if that isn't set
+func DataDir() (string, error) {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "tetris"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share", "tetris"), nil
+}
+
+// where the high score table is saved
+func DefaultPath() (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "scores.json"), nil
+}
+
+// loads the table from a file, if the file doesn't exist yet the table is empty
+func Load(path string) (*Table, error) {
+	t := &Table{Modes: map[string][]Entry{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, t); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	if t.Modes == nil {
+		t.Modes = map[string][]Entry{}
+	}
+	return t, nil
+}
+
+// saves the table to a file, making the directory it goes in if it needs to
+func (t *Table) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(t, "", "\t")
+	if err != nil {
+		return err
+	}
+	// writing to a temporary file first so a crash can't leave half a table behind
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// would the entry make it into the table for a ruleset ranked by order
+func (t *Table) Qualifies(r Ruleset, order Order, e Entry) bool {
+	entries := t.Modes[r.Key()]
+	return len(entries) < MaxEntries || order.better(e, entries[len(entries)-1])
+}
+
+// adds an entry to the table for a ruleset ranked by order, dropping the worst entry if the table is full,
+// the entry is given the board and piece set of the ruleset,
+// returns where the entry ended up starting at 0, or -1 if it didn't make it in
+func (t *Table) Add(r Ruleset, order Order, e Entry) int {
+	e.Board, e.Pieces = r.Board, r.Pieces
+	entries := append(t.Modes[r.Key()], e)
+	// a stable sort keeps older entries above newer ones that are just as good
+	sort.SliceStable(entries, func(i, j int) bool {
+		return order.better(entries[i], entries[j])
+	})
+	if len(entries) > MaxEntries {
+		entries = entries[:MaxEntries]
+	}
+	t.Modes[r.Key()] = entries
+
+	for i := range entries {
+		if entries[i] == e {
+			return i
+		}
+	}
+	return -1
+}
+
+// the entries for a ruleset, best first
+func (t *Table) Top(r Ruleset) []Entry {
+	return t.Modes[r.Key()]
+}
+
+// the best entry for a ruleset, the personal best, and whether there is one
+func (t *Table) Best(r Ruleset) (Entry, bool) {
+	entries := t.Modes[r.Key()]
+	if len(entries) == 0 {
+		return Entry{}, false
+	}
+	return entries[0], true
+}
+
+// the keys of the rulesets that have scores in the table, in alphabetical order
+func (t *Table) Keys() []string {
+	var keys []string
+	for key := range t.Modes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writes the table for a ruleset key as text, one line per entry
+func (t *Table) Print(w io.Writer, key string) {
+	fmt.Fprintf(w, "%s\n", key)
+	entries := t.Modes[key]
+	if len(entries) == 0 {
+		fmt.Fprintln(w, "  no scores yet")
+	}
+	for i, e := range entries {
+		fmt.Fprintf(w, "%2d. %-12s %8d  level %-3d lines %-4d %s  %s\n", i+1, e.Name, e.Score, e.Level, e.Lines, FormatTime(e.Time), e.Rules())
+	}
+}
+
+// the board size and piece set of the entry, like "10x20 tetrominoes", or "" if it was saved before they were kept
+func (e Entry) Rules() string {
+	return strings.TrimSpace(e.Board + " " + e.Pieces)
+}
+
+// formats a game time as minutes, seconds and milliseconds
+func FormatTime(d time.Duration) string {
+	d = d.Round(time.Millisecond)
+	return fmt.Sprintf("%d:%02d.%03d", int(d.Minutes()), int(d.Seconds())%60, int(d.Milliseconds())%1000)
+}
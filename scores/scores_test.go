@@ -0,0 +1,120 @@
+package scores
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// a standard game of the mode, with everything else left at its default
+func standard(mode string) Ruleset {
+	r := Standard
+	r.Mode = mode
+	return r
+}
+
+func TestRulesetKey(t *testing.T) {
+	tests := []struct {
+		change func(r *Ruleset)
+		want   string
+	}{
+		{func(r *Ruleset) {}, "sprint"},
+		{func(r *Ruleset) { r.Board = "12x24" }, "sprint 12x24"},
+		{func(r *Ruleset) { r.Pieces = "pentominoes" }, "sprint pentominoes"},
+		{func(r *Ruleset) { r.StartLevel = 5 }, "sprint level 5"},
+		{func(r *Ruleset) { r.Gravity = "20g" }, "sprint 20g gravity"},
+		{func(r *Ruleset) { r.Randomizer = "history" }, "sprint history"},
+		{func(r *Ruleset) { r.Board, r.Pieces = "6x12", "small" }, "sprint 6x12 small"},
+	}
+	for _, test := range tests {
+		r := standard("sprint")
+		test.change(&r)
+		if got := r.Key(); got != test.want {
+			t.Errorf("the key of %+v is %q, want %q", r, got, test.want)
+		}
+	}
+}
+
+// games played to other rules go in tables of their own, however well they did
+func TestRulesetsRankedApart(t *testing.T) {
+	table := &Table{Modes: map[string][]Entry{}}
+	sprint := standard("sprint")
+	small := standard("sprint")
+	small.Board, small.Pieces = "6x12", "small"
+
+	table.Add(sprint, ByTime, Entry{Name: "standard", Time: time.Minute})
+	if rank := table.Add(small, ByTime, Entry{Name: "small", Time: 10 * time.Second}); rank != 0 {
+		t.Fatalf("the small game went in at %d, want 0", rank)
+	}
+
+	best, ok := table.Best(sprint)
+	if !ok || best.Name != "standard" {
+		t.Fatalf("the best standard sprint is %+v, want the standard game", best)
+	}
+	if got := table.Top(small); len(got) != 1 || got[0].Board != "6x12" || got[0].Pieces != "small" {
+		t.Fatalf("the small sprints are %+v, want the small game with its board and pieces", got)
+	}
+	if got, want := table.Keys(), []string{"sprint", "sprint 6x12 small"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("the table has %q, want %q", got, want)
+	}
+
+	var out bytes.Buffer
+	table.Print(&out, small.Key())
+	if !strings.Contains(out.String(), "6x12 small") {
+		t.Fatalf("the printed table doesn't show the board and pieces:\n%s", out.String())
+	}
+}
+
+func TestAddKeepsTheBest(t *testing.T) {
+	table := &Table{Modes: map[string][]Entry{}}
+	marathon := standard("marathon")
+	for i := 1; i <= MaxEntries; i++ {
+		table.Add(marathon, ByScore, Entry{Score: i * 100})
+	}
+	if table.Qualifies(marathon, ByScore, Entry{Score: 100}) {
+		t.Fatal("a score no better than the worst one in a full table qualifies")
+	}
+	if rank := table.Add(marathon, ByScore, Entry{Score: 550}); rank != 5 {
+		t.Fatalf("550 went in at %d, want 5", rank)
+	}
+	entries := table.Top(marathon)
+	if len(entries) != MaxEntries || entries[0].Score != 1000 || entries[len(entries)-1].Score != 200 {
+		t.Fatalf("the table is %+v, want the %d best from 1000 down to 200", entries, MaxEntries)
+	}
+	if rank := table.Add(marathon, ByScore, Entry{Score: 50}); rank != -1 {
+		t.Fatalf("50 went in at %d, want -1", rank)
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "scores.json")
+	table, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(table.Keys()) != 0 {
+		t.Fatalf("a missing file gave a table with %q in it", table.Keys())
+	}
+
+	ultra := standard("ultra")
+	ultra.StartLevel = 10
+	table.Add(ultra, ByScore, Entry{Name: "a", Score: 1234, Time: 3 * time.Minute, Date: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)})
+	if err := table.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := loaded.Best(ultra)
+	want, _ := table.Best(ultra)
+	if !ok || !got.Date.Equal(want.Date) {
+		t.Fatalf("the loaded best is %+v, want %+v", got, want)
+	}
+	got.Date = want.Date
+	if got != want {
+		t.Fatalf("the loaded best is %+v, want %+v", got, want)
+	}
+}
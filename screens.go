@@ -0,0 +1,269 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/pixelgl"
+	"github.com/faiface/pixel/text"
+
+	"tetris/engine"
+	"tetris/scores"
+	"tetris/stats"
+)
+
+// the longest name that can be typed into the high score table
+const MaxNameLength = 12
+
+// how the high score table for a mode is ranked, races are ranked by time and everything else by score
+func score_order(mode string) scores.Order {
+	switch mode {
+	case "sprint", "dig":
+		return scores.ByTime
+	}
+	return scores.ByScore
+}
+
+// the ruleset a game was played to, which decides the high score table it is ranked in
+func score_ruleset(game *engine.Game) scores.Ruleset {
+	return scores.Ruleset{
+		Mode:       game.Mode.Name(),
+		Board:      fmt.Sprintf("%dx%d", game.PlayingBoard.Width, game.PlayingBoard.Visible),
+		Pieces:     game.Pieces.Name,
+		StartLevel: game.StartLevel,
+		Gravity:    string(game.Gravity),
+		Randomizer: game.Randomizer.Name(),
+	}
+}
+
+// the screen shown over the board once the game is over, or once the goal of the mode has been reached
+type gameOverScreen struct {
+	// the mode and the rest of the rules the game was played to, which high score table it goes into
+	ruleset scores.Ruleset
+
+	// the finished game, as it will go into the high score table
+	entry scores.Entry
+
+	// did the game end by reaching the goal of the mode rather than by topping out
+	finished bool
+
+	// the split times of a sprint, nil for other modes, taken every split_lines lines
+	splits      []time.Duration
+	split_lines int
+
+	// the stats of the game
+	stats stats.Stats
+
+	// how the game was lost, if it was
+	reason engine.GameOverReason
+
+	// the personal best from before this game, if there was one
+	best     scores.Entry
+	has_best bool
+
+	// are we still typing a name for the high score table
+	entering bool
+
+	// where the game landed in the high score table, -1 if it didn't make it
+	rank int
+}
+
+// makes the game over screen for a finished game, asking for a name if it made the high score table,
+// a race only counts if it was finished
+func new_game_over_screen(game *engine.Game, table *scores.Table, stats stats.Stats) *gameOverScreen {
+	s := &gameOverScreen{
+		ruleset: score_ruleset(game),
+		entry: scores.Entry{
+			Score: game.Score,
+			Level: game.Level,
+			Lines: game.LinesCleared,
+			Time:  game.PlayTime,
+			Date:  time.Now(),
+		},
+		finished: game.Finished,
+		stats:    stats,
+		reason:   game.GameOverReason,
+		rank:     -1,
+	}
+	if sprint, ok := game.Mode.(*engine.Sprint); ok {
+		s.splits = sprint.Splits
+		s.split_lines = sprint.SplitLines
+	}
+	if table != nil {
+		s.best, s.has_best = table.Best(s.ruleset)
+		counts := game.Finished || score_order(s.ruleset.Mode) == scores.ByScore
+		s.entering = counts && table.Qualifies(s.ruleset, score_order(s.ruleset.Mode), s.entry)
+	}
+	return s
+}
+
+// handles typing a name into the high score table, saving it when enter is pressed,
+// returns true once the screen is done and the window can close
+func (s *gameOverScreen) update(win *pixelgl.Window, table *scores.Table, scores_path string) bool {
+	if !s.entering {
+		return win.JustPressed(pixelgl.KeyEnter) || win.JustPressed(pixelgl.KeyEscape)
+	}
+
+	for _, r := range win.Typed() {
+		if r >= ' ' && r <= '~' && len(s.entry.Name) < MaxNameLength {
+			s.entry.Name += string(r)
+		}
+	}
+	if (win.JustPressed(pixelgl.KeyBackspace) || win.Repeated(pixelgl.KeyBackspace)) && len(s.entry.Name) > 0 {
+		s.entry.Name = s.entry.Name[:len(s.entry.Name)-1]
+	}
+	if win.JustPressed(pixelgl.KeyEnter) {
+		s.entering = false
+		if strings.TrimSpace(s.entry.Name) == "" {
+			s.entry.Name = "Player"
+		}
+		s.rank = table.Add(s.ruleset, score_order(s.ruleset.Mode), s.entry)
+		if scores_path != "" {
+			if err := table.Save(scores_path); err != nil {
+				fmt.Fprintln(os.Stderr, "saving high scores:", err)
+			}
+		}
+	}
+	return false
+}
+
+// draws the game over screen with its top left corner at pos
+func (s *gameOverScreen) draw(win *pixelgl.Window, atlas *text.Atlas, pos pixel.Vec, table *scores.Table) {
+	txt := text.New(pos, atlas)
+	if s.finished {
+		fmt.Fprintln(txt, "FINISHED")
+	} else {
+		fmt.Fprintln(txt, "GAME OVER")
+		fmt.Fprintln(txt, s.reason)
+	}
+	fmt.Fprintln(txt)
+	fmt.Fprintf(txt, "Score  %d\n", s.entry.Score)
+	fmt.Fprintf(txt, "Level  %d\n", s.entry.Level)
+	fmt.Fprintf(txt, "Lines  %d\n", s.entry.Lines)
+	fmt.Fprintf(txt, "Time   %s\n", scores.FormatTime(s.entry.Time))
+	fmt.Fprintf(txt, "PPS    %.2f\n", s.stats.PPS)
+	fmt.Fprintf(txt, "APM    %.1f\n", s.stats.APM)
+	fmt.Fprintf(txt, "KPP    %.2f\n", s.stats.KPP)
+	for i, split := range s.splits {
+		fmt.Fprintf(txt, "  %3d  %s\n", (i+1)*s.split_lines, scores.FormatTime(split))
+	}
+
+	// comparing a game that reached its goal against the personal best from before it
+	switch {
+	case !s.finished:
+	case !s.has_best:
+		fmt.Fprintln(txt, "First finish!")
+	case score_order(s.ruleset.Mode) == scores.ByTime && s.entry.Time < s.best.Time:
+		fmt.Fprintf(txt, "New best! -%s\n", scores.FormatTime(s.best.Time-s.entry.Time))
+	case score_order(s.ruleset.Mode) == scores.ByTime:
+		fmt.Fprintf(txt, "Best   %s (+%s)\n", scores.FormatTime(s.best.Time), scores.FormatTime(s.entry.Time-s.best.Time))
+	case s.entry.Score > s.best.Score:
+		fmt.Fprintf(txt, "New best! +%d\n", s.entry.Score-s.best.Score)
+	default:
+		fmt.Fprintf(txt, "Best   %d (-%d)\n", s.best.Score, s.best.Score-s.entry.Score)
+	}
+	fmt.Fprintln(txt)
+	if s.entering {
+		fmt.Fprintln(txt, "New high score!")
+		fmt.Fprintf(txt, "Name: %s_\n", s.entry.Name)
+		fmt.Fprintln(txt, "Press enter to save")
+	} else {
+		draw_scores(txt, table, s.ruleset, s.rank)
+		fmt.Fprintln(txt)
+		fmt.Fprintln(txt, "Press enter to quit")
+	}
+	txt.Draw(win, pixel.IM)
+}
+
+// menu is a list of options picked with the up and down arrows and enter
+type menu struct {
+	options  []string
+	selected int
+}
+
+func new_menu(options ...string) *menu {
+	return &menu{options: options}
+}
+
+// moves the selection with the arrow keys, returns the option picked with enter, or -1 until one is picked
+func (m *menu) update(win *pixelgl.Window) int {
+	if win.JustPressed(pixelgl.KeyUp) || win.Repeated(pixelgl.KeyUp) {
+		m.selected = (m.selected + len(m.options) - 1) % len(m.options)
+	}
+	if win.JustPressed(pixelgl.KeyDown) || win.Repeated(pixelgl.KeyDown) {
+		m.selected = (m.selected + 1) % len(m.options)
+	}
+	if win.JustPressed(pixelgl.KeyEnter) {
+		return m.selected
+	}
+	return -1
+}
+
+// writes the options into w, one per line, marking the selected one
+func (m *menu) draw(w io.Writer) {
+	for i, option := range m.options {
+		marker := " "
+		if i == m.selected {
+			marker = ">"
+		}
+		fmt.Fprintf(w, "%s %s\n", marker, option)
+	}
+}
+
+// writes the high score table for a ruleset into w, marking the entry at highlight (-1 for none),
+// races show their times instead of their scores
+func draw_scores(w io.Writer, table *scores.Table, ruleset scores.Ruleset, highlight int) {
+	if table == nil {
+		return
+	}
+	fmt.Fprintf(w, "High scores - %s\n", ruleset.Key())
+	entries := table.Top(ruleset)
+	if len(entries) == 0 {
+		fmt.Fprintln(w, "no scores yet")
+	}
+	for i, e := range entries {
+		marker := " "
+		if i == highlight {
+			marker = ">"
+		}
+		if score_order(ruleset.Mode) == scores.ByTime {
+			fmt.Fprintf(w, "%s%2d. %-*s %s  %s\n", marker, i+1, MaxNameLength, e.Name, scores.FormatTime(e.Time), e.Rules())
+		} else {
+			fmt.Fprintf(w, "%s%2d. %-*s %d  %s\n", marker, i+1, MaxNameLength, e.Name, e.Score, e.Rules())
+		}
+	}
+}
+
+// loads the high score table, if it can't be read it is left nil so it never gets overwritten
+func load_scores() (*scores.Table, string) {
+	path, err := scores.DefaultPath()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "finding high scores:", err)
+		return nil, ""
+	}
+	table, err := scores.Load(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "loading high scores:", err)
+		return nil, ""
+	}
+	return table, path
+}
+
+// prints the table of every mode and ruleset in the high score table, for the -scores flag
+func print_scores() {
+	table, _ := load_scores()
+	if table == nil {
+		os.Exit(1)
+	}
+	if len(table.Keys()) == 0 {
+		fmt.Println("no scores yet")
+	}
+	for _, key := range table.Keys() {
+		table.Print(os.Stdout, key)
+		fmt.Println()
+	}
+}
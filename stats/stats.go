@@ -0,0 +1,177 @@
+// Package stats keeps the numbers people compare their play by, like pieces per second and attack per minute,
+// a Collector listens to a game's clears and keeps its Stats up to date as the game goes
+package stats
+
+import (
+	"encoding/json"
+	"math/bits"
+	"os"
+	"time"
+
+	"tetris/engine"
+)
+
+// Stats is everything collected about a game so far
+type Stats struct {
+	Mode string `json:"mode"`
+
+	// how long the game has been played for, not counting time spent paused
+	PlayTime time.Duration `json:"play_time"`
+
+	// how many pieces were locked, and how many keys were pressed to move and rotate them
+	Pieces int `json:"pieces"`
+	Keys   int `json:"keys"`
+
+	// how many lines of garbage the clears would send by the attack table
+	Attack int `json:"attack"`
+
+	// pieces per second, keys per piece and attack per minute
+	PPS float64 `json:"pps"`
+	KPP float64 `json:"kpp"`
+	APM float64 `json:"apm"`
+
+	Lines    int `json:"lines"`
+	Singles  int `json:"singles"`
+	Doubles  int `json:"doubles"`
+	Triples  int `json:"triples"`
+	Tetrises int `json:"tetrises"`
+
+	// t-spins by how many lines they cleared, including the ones that didn't clear any
+	TSpinMinis   int `json:"tspin_minis"`
+	TSpinZeros   int `json:"tspin_zeros"`
+	TSpinSingles int `json:"tspin_singles"`
+	TSpinDoubles int `json:"tspin_doubles"`
+	TSpinTriples int `json:"tspin_triples"`
+
+	PerfectClears int `json:"perfect_clears"`
+	BackToBacks   int `json:"back_to_backs"`
+
+	// the longest run of pieces that each cleared lines, counted the way the combo popup is
+	MaxCombo int `json:"max_combo"`
+
+	// how many holes were in the stack after each piece locked
+	Holes []HoleSample `json:"holes"`
+}
+
+// HoleSample is how many holes there were in the stack when a piece locked
+type HoleSample struct {
+	// which piece it was, starting at 1, and how far into the game it locked
+	Piece    int           `json:"piece"`
+	PlayTime time.Duration `json:"play_time"`
+
+	Holes int `json:"holes"`
+}
+
+// Collector keeps the stats of one game
+type Collector struct {
+	game   *engine.Game
+	attack engine.AttackTable
+	stats  Stats
+
+	// play time from before the game started, when the stats carry on from an earlier game
+	earlier time.Duration
+}
+
+// starts collecting the stats of the game, working out attack by the attack table,
+// this has to be called before the first piece locks so nothing is missed
+func NewCollector(g *engine.Game, attack engine.AttackTable) *Collector {
+	c := &Collector{game: g, attack: attack}
+	c.stats.Mode = g.Mode.Name()
+	g.OnClear(c.add)
+	return c
+}
+
+// carries on from stats collected before the game was saved, for a game that was carried on from a save,
+// or from the stats of an earlier game, for finesse training which starts a new game after every fault
+func (c *Collector) Resume(s Stats) {
+	c.stats = s
+	c.stats.Holes = append([]HoleSample(nil), s.Holes...)
+	c.earlier = s.PlayTime - c.game.PlayTime
+}
+
+// how long has been played, counting the games these stats carry on from
+func (c *Collector) playTime() time.Duration {
+	return c.earlier + c.game.PlayTime
+}
+
+// counts a piece that locked
+func (c *Collector) add(e engine.ClearEvent) {
+	s := &c.stats
+	s.Pieces++
+	s.Keys += e.Keys
+	s.Attack += c.attack.Attack(e)
+	s.Lines += e.Lines
+
+	switch e.TSpin {
+	case engine.TSpinMini:
+		s.TSpinMinis++
+	case engine.TSpinFull:
+		switch e.Lines {
+		case 0:
+			s.TSpinZeros++
+		case 1:
+			s.TSpinSingles++
+		case 2:
+			s.TSpinDoubles++
+		case 3:
+			s.TSpinTriples++
+		}
+	default:
+		switch e.Lines {
+		case 1:
+			s.Singles++
+		case 2:
+			s.Doubles++
+		case 3:
+			s.Triples++
+		case 4:
+			s.Tetrises++
+		}
+	}
+	if e.PerfectClear {
+		s.PerfectClears++
+	}
+	if e.BackToBack {
+		s.BackToBacks++
+	}
+	if e.Combo > s.MaxCombo {
+		s.MaxCombo = e.Combo
+	}
+
+	s.Holes = append(s.Holes, HoleSample{Piece: s.Pieces, PlayTime: c.playTime(), Holes: Holes(&c.game.PlayingBoard)})
+}
+
+// the stats so far, with the rates worked out for how long the game has been played
+func (c *Collector) Stats() Stats {
+	s := c.stats
+	s.Holes = append([]HoleSample(nil), c.stats.Holes...)
+	s.PlayTime = c.playTime()
+	if seconds := s.PlayTime.Seconds(); seconds > 0 {
+		s.PPS = float64(s.Pieces) / seconds
+		s.APM = float64(s.Attack) / seconds * 60
+	}
+	if s.Pieces > 0 {
+		s.KPP = float64(s.Keys) / float64(s.Pieces)
+	}
+	return s
+}
+
+// how many empty pixels have something above them in the same column
+func Holes(board *engine.Board) int {
+	holes := 0
+	var covered uint16
+	for i := board.Height - 1; i >= 0; i-- {
+		holes += bits.OnesCount16(covered &^ board.Rows[i])
+		covered |= board.Rows[i]
+	}
+	return holes
+}
+
+// writes the stats to a file as JSON
+func (s Stats) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,139 @@
+package stats
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"tetris/engine"
+)
+
+// a game of locks, each sent to the collector as if the piece had just locked
+var events = []engine.ClearEvent{
+	{Lines: 1, Keys: 3},
+	{Lines: 2, Combo: 1, Keys: 3},
+	{Lines: 4, Combo: 2, Keys: 3},
+	{TSpin: engine.TSpinFull, Keys: 3},
+	{TSpin: engine.TSpinMini, Keys: 3},
+	{Lines: 2, TSpin: engine.TSpinFull, BackToBack: true, Keys: 3},
+	{Lines: 3, Combo: 1, PerfectClear: true, Keys: 3},
+	{Lines: 1, TSpin: engine.TSpinMini, Keys: 3},
+}
+
+func newTestCollector() (*engine.Game, *Collector) {
+	g := engine.NewGame(1, engine.NewBagRandomizer(1, 1))
+	return &g, NewCollector(&g, engine.DefaultAttackTable())
+}
+
+func TestCounts(t *testing.T) {
+	g, c := newTestCollector()
+	for _, e := range events {
+		c.add(e)
+	}
+	g.PlayTime = 10 * time.Second
+	s := c.Stats()
+
+	want := Stats{
+		Mode:          "marathon",
+		PlayTime:      10 * time.Second,
+		Pieces:        8,
+		Keys:          24,
+		Attack:        0 + 2 + 5 + 0 + 0 + 5 + 13 + 0,
+		PPS:           0.8,
+		KPP:           3,
+		APM:           150,
+		Lines:         13,
+		Singles:       1,
+		Doubles:       1,
+		Triples:       1,
+		Tetrises:      1,
+		TSpinMinis:    2,
+		TSpinZeros:    1,
+		TSpinDoubles:  1,
+		PerfectClears: 1,
+		BackToBacks:   1,
+		MaxCombo:      2,
+	}
+	s.Holes = nil
+	if !reflect.DeepEqual(s, want) {
+		t.Fatalf("got %+v\nwant %+v", s, want)
+	}
+}
+
+func TestNoPlayTime(t *testing.T) {
+	_, c := newTestCollector()
+	if s := c.Stats(); s.PPS != 0 || s.KPP != 0 || s.APM != 0 {
+		t.Fatalf("rates before anything was played are %v pps, %v kpp and %v apm, want 0", s.PPS, s.KPP, s.APM)
+	}
+}
+
+func TestHoles(t *testing.T) {
+	g, c := newTestCollector()
+	for i, row := range []string{"X.XXXXXXXX", "XXXXXX.XXX", "......X..."} {
+		for j, pixel := range row {
+			if pixel == 'X' {
+				g.PlayingBoard.Set(engine.Point{Row: i, Col: j}, engine.GarbagePixel)
+			}
+		}
+	}
+	if holes := Holes(&g.PlayingBoard); holes != 2 {
+		t.Fatalf("%d holes, want 2", holes)
+	}
+
+	g.PlayTime = time.Second
+	c.add(engine.ClearEvent{})
+	want := []HoleSample{{Piece: 1, PlayTime: time.Second, Holes: 2}}
+	if got := c.Stats().Holes; !reflect.DeepEqual(got, want) {
+		t.Fatalf("hole samples %+v, want %+v", got, want)
+	}
+}
+
+func TestResume(t *testing.T) {
+	g, c := newTestCollector()
+	for _, e := range events {
+		c.add(e)
+	}
+	g.PlayTime = 10 * time.Second
+	before := c.Stats()
+
+	// carrying on in a new game, like finesse training does after a fault, counts the earlier game's play time too
+	g, c = newTestCollector()
+	c.Resume(before)
+	g.PlayTime = 5 * time.Second
+	c.add(events[0])
+	s := c.Stats()
+	if s.Pieces != 9 || s.PlayTime != 15*time.Second || s.PPS != 0.6 {
+		t.Fatalf("after carrying on %d pieces in %v at %v pps, want 9 pieces in 15s at 0.6 pps", s.Pieces, s.PlayTime, s.PPS)
+	}
+	if last := s.Holes[len(s.Holes)-1]; last.Piece != 9 || last.PlayTime != 15*time.Second {
+		t.Fatalf("the last hole sample is %+v, want piece 9 at 15s", last)
+	}
+}
+
+func TestSaveRoundTrip(t *testing.T) {
+	g, c := newTestCollector()
+	for _, e := range events {
+		g.PlayTime += time.Second
+		c.add(e)
+	}
+	s := c.Stats()
+
+	path := filepath.Join(t.TempDir(), "stats.json")
+	if err := s.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var read Stats
+	if err := json.Unmarshal(data, &read); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, s) {
+		t.Fatalf("read back %+v\nwant %+v", read, s)
+	}
+}